@@ -0,0 +1,320 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"it_inventar/models"
+	"it_inventar/models/Storage"
+	"it_inventar/models/Validation"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// DefaultConfigFile is looked up in the working directory and next to the executable
+const DefaultConfigFile = "config.json"
+
+// HistoryFileName is the file in the data directory that keeps the undo and redo history
+const HistoryFileName = "inventar.history.json"
+
+// AttributesFileName is the file in the data directory that defines the attributes of the categories
+const AttributesFileName = "inventar.attributes.csv"
+
+// EmployeesFileName is the file in the data directory that keeps the employee register
+const EmployeesFileName = "inventar.employees.csv"
+
+// LocationsFileName is the file in the data directory that lists the locations the stock is kept at
+const LocationsFileName = "inventar.locations.csv"
+
+// MovementsFileName is the file in the data directory that keeps the ledger of the stock movements
+const MovementsFileName = "inventar.movements.csv"
+
+// envPrefix starts the names of all environment variables read by Load
+const envPrefix = "IT_INVENTAR_"
+
+// Config holds all settings of the application.
+// The values are taken from the defaults, the config file, the environment and the command line, in that order.
+type Config struct {
+	DataDir          string `json:"dataDir"`
+	DataFile         string `json:"dataFile"`
+	CategoriesFile   string `json:"categoriesFile"`
+	SupplierFile     string `json:"supplierFile"`
+	PageSize         int    `json:"pageSize"`
+	BackupDir        string `json:"backupDir"`
+	BackupCount      int    `json:"backupCount"`
+	BackupMaxAgeDays int    `json:"backupMaxAgeDays"`
+	PurgeAfterDays   int    `json:"purgeAfterDays"`
+	UndoLevels       int    `json:"undoLevels"`
+	NamePunctuation  string `json:"namePunctuation"`
+	NameMaxLength    int    `json:"nameMaxLength"`
+	// UniqueNumbersIncludeDeleted makes the article numbers of deleted items count as taken as well
+	UniqueNumbersIncludeDeleted bool `json:"uniqueNumbersIncludeDeleted"`
+
+	// ConfigFile is the file the settings were read from, empty if none was found
+	ConfigFile string `json:"-"`
+	// InitMode creates missing data files without asking, it is only set on the command line
+	InitMode string `json:"-"`
+}
+
+// Values of InitMode
+const (
+	InitEmpty    = "empty"
+	InitDefaults = "defaults"
+)
+
+// Default returns the settings used when nothing else is configured
+func Default() Config {
+	return Config{
+		DataDir:          ".",
+		DataFile:         models.FileData,
+		CategoriesFile:   models.FileCategories,
+		SupplierFile:     models.FileSupplier,
+		PageSize:         10,
+		BackupCount:      Storage.Backups.MaxCount,
+		BackupMaxAgeDays: int(Storage.Backups.MaxAge / (24 * time.Hour)),
+		UndoLevels:       20,
+		NamePunctuation:  Validation.DefaultPunctuation,
+		NameMaxLength:    Validation.DefaultMaxLength,
+	}
+}
+
+// Load reads the settings from the config file, the environment variables and the passed command line arguments
+func Load(args []string) (Config, error) {
+	settings := Default()
+
+	flags := flag.NewFlagSet("it_inventar", flag.ContinueOnError)
+	configFile := flags.String("config", "", "path of the config file (default: "+DefaultConfigFile+")")
+	dataDir := flags.String("data-dir", "", "directory of the data files")
+	dataFile := flags.String("data-file", "", "name of the inventory file")
+	categoriesFile := flags.String("categories-file", "", "name of the categories file")
+	supplierFile := flags.String("supplier-file", "", "name of the supplier file")
+	pageSize := flags.Int("page-size", 0, "number of rows per page")
+	backupDir := flags.String("backup-dir", "", "directory of the snapshots (default: backups in the data directory)")
+	backupCount := flags.Int("backup-count", 0, "number of snapshots kept per file, 0 keeps all")
+	backupMaxAgeDays := flags.Int("backup-max-age-days", 0, "days after which snapshots are removed, 0 keeps them forever")
+	purgeAfterDays := flags.Int("purge-after-days", 0, "days after which deleted items are moved to the archive, 0 keeps them")
+	undoLevels := flags.Int("undo-levels", 0, "number of changes that can be undone, 0 disables undo")
+	namePunctuation := flags.String("name-punctuation", "", "characters allowed in names besides letters, digits and spaces")
+	nameMaxLength := flags.Int("name-max-length", 0, "maximum number of characters of a name, 0 allows any length")
+	uniqueNumbersIncludeDeleted := flags.Bool("unique-numbers-include-deleted", false, "article numbers of deleted items count as taken as well")
+	flags.StringVar(&settings.InitMode, "init", "", "create missing data files without asking: "+InitEmpty+" or "+InitDefaults+" (default IT categories)")
+	if err := flags.Parse(args); err != nil {
+		return settings, err
+	}
+
+	// Config file
+	path := firstNonEmpty(*configFile, os.Getenv(envPrefix+"CONFIG"))
+	if err := settings.readFile(path); err != nil {
+		return settings, err
+	}
+
+	// Environment variables
+	settings.DataDir = firstNonEmpty(os.Getenv(envPrefix+"DATA_DIR"), settings.DataDir)
+	settings.DataFile = firstNonEmpty(os.Getenv(envPrefix+"DATA_FILE"), settings.DataFile)
+	settings.CategoriesFile = firstNonEmpty(os.Getenv(envPrefix+"CATEGORIES_FILE"), settings.CategoriesFile)
+	settings.SupplierFile = firstNonEmpty(os.Getenv(envPrefix+"SUPPLIER_FILE"), settings.SupplierFile)
+	settings.BackupDir = firstNonEmpty(os.Getenv(envPrefix+"BACKUP_DIR"), settings.BackupDir)
+	settings.NamePunctuation = firstNonEmpty(os.Getenv(envPrefix+"NAME_PUNCTUATION"), settings.NamePunctuation)
+	if value := os.Getenv(envPrefix + "UNIQUE_NUMBERS_INCLUDE_DELETED"); value != "" {
+		include, err := strconv.ParseBool(value)
+		if err != nil {
+			return settings, fmt.Errorf("environment variable %sUNIQUE_NUMBERS_INCLUDE_DELETED must be true or false: %v", envPrefix, err)
+		}
+		settings.UniqueNumbersIncludeDeleted = include
+	}
+	for name, target := range map[string]*int{
+		"PAGE_SIZE":           &settings.PageSize,
+		"BACKUP_COUNT":        &settings.BackupCount,
+		"BACKUP_MAX_AGE_DAYS": &settings.BackupMaxAgeDays,
+		"PURGE_AFTER_DAYS":    &settings.PurgeAfterDays,
+		"UNDO_LEVELS":         &settings.UndoLevels,
+		"NAME_MAX_LENGTH":     &settings.NameMaxLength,
+	} {
+		if value := os.Getenv(envPrefix + name); value != "" {
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return settings, fmt.Errorf("environment variable %s%s must be a number: %v", envPrefix, name, err)
+			}
+			*target = number
+		}
+	}
+
+	// Command line flags, only the ones that were passed override the other sources
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "data-dir":
+			settings.DataDir = *dataDir
+		case "data-file":
+			settings.DataFile = *dataFile
+		case "categories-file":
+			settings.CategoriesFile = *categoriesFile
+		case "supplier-file":
+			settings.SupplierFile = *supplierFile
+		case "page-size":
+			settings.PageSize = *pageSize
+		case "backup-dir":
+			settings.BackupDir = *backupDir
+		case "backup-count":
+			settings.BackupCount = *backupCount
+		case "backup-max-age-days":
+			settings.BackupMaxAgeDays = *backupMaxAgeDays
+		case "purge-after-days":
+			settings.PurgeAfterDays = *purgeAfterDays
+		case "undo-levels":
+			settings.UndoLevels = *undoLevels
+		case "name-punctuation":
+			settings.NamePunctuation = *namePunctuation
+		case "name-max-length":
+			settings.NameMaxLength = *nameMaxLength
+		case "unique-numbers-include-deleted":
+			settings.UniqueNumbersIncludeDeleted = *uniqueNumbersIncludeDeleted
+		}
+	})
+
+	return settings, settings.validate()
+}
+
+// DataPath returns the path of the inventory file
+func (c Config) DataPath() string {
+	return c.resolve(c.DataFile)
+}
+
+// CategoriesPath returns the path of the categories file
+func (c Config) CategoriesPath() string {
+	return c.resolve(c.CategoriesFile)
+}
+
+// SupplierPath returns the path of the supplier file
+func (c Config) SupplierPath() string {
+	return c.resolve(c.SupplierFile)
+}
+
+// HistoryPath returns the path of the undo and redo history
+func (c Config) HistoryPath() string {
+	return c.resolve(HistoryFileName)
+}
+
+// AttributesPath returns the path of the attribute definitions of the categories
+func (c Config) AttributesPath() string {
+	return c.resolve(AttributesFileName)
+}
+
+// EmployeesPath returns the path of the employee register
+func (c Config) EmployeesPath() string {
+	return c.resolve(EmployeesFileName)
+}
+
+// LocationsPath returns the path of the list of locations
+func (c Config) LocationsPath() string {
+	return c.resolve(LocationsFileName)
+}
+
+// MovementsPath returns the path of the ledger of the stock movements
+func (c Config) MovementsPath() string {
+	return c.resolve(MovementsFileName)
+}
+
+// NameRules returns the rules names of categories, suppliers and attributes must follow
+func (c Config) NameRules() Validation.NameRules {
+	return Validation.NameRules{Punctuation: c.NamePunctuation, MaxLength: c.NameMaxLength}
+}
+
+// BackupPolicy returns the snapshot settings for the Storage package
+func (c Config) BackupPolicy() Storage.BackupPolicy {
+	policy := Storage.BackupPolicy{
+		MaxCount: c.BackupCount,
+		MaxAge:   time.Duration(c.BackupMaxAgeDays) * 24 * time.Hour,
+	}
+	if c.BackupDir != "" {
+		policy.Dir = c.resolve(c.BackupDir)
+	}
+	return policy
+}
+
+// resolve places a relative file name inside the data directory
+func (c Config) resolve(fileName string) string {
+	if filepath.IsAbs(fileName) {
+		return fileName
+	}
+	return filepath.Join(c.DataDir, fileName)
+}
+
+// readFile reads the config file. Without an explicit path the default file is optional.
+func (c *Config) readFile(path string) error {
+	explicit := path != ""
+	if !explicit {
+		path = findDefaultConfigFile()
+		if path == "" {
+			return nil
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("reading config file: %v", err)
+	}
+	// The data directory is cleared first, so a file without dataDir keeps the default instead of the config directory
+	defaultDataDir := c.DataDir
+	c.DataDir = ""
+	if err := json.Unmarshal(content, c); err != nil {
+		c.DataDir = defaultDataDir
+		return fmt.Errorf("config file %s is invalid: %v", path, err)
+	}
+	c.ConfigFile = path
+
+	// A relative data directory is resolved against the directory of the config file
+	switch {
+	case c.DataDir == "":
+		c.DataDir = defaultDataDir
+	case !filepath.IsAbs(c.DataDir):
+		c.DataDir = filepath.Join(filepath.Dir(path), c.DataDir)
+	}
+	return nil
+}
+
+// validate rejects settings the application cannot work with
+func (c Config) validate() error {
+	if c.DataDir == "" || c.DataFile == "" || c.CategoriesFile == "" || c.SupplierFile == "" {
+		return errors.New("the data directory and the file names must not be empty")
+	}
+	if c.PageSize < 1 {
+		return fmt.Errorf("the page size must be at least 1, got %d", c.PageSize)
+	}
+	if c.BackupCount < 0 || c.BackupMaxAgeDays < 0 || c.PurgeAfterDays < 0 || c.UndoLevels < 0 || c.NameMaxLength < 0 {
+		return errors.New("the backup count, the undo levels, the name length and the numbers of days must not be negative")
+	}
+	if c.InitMode != "" && c.InitMode != InitEmpty && c.InitMode != InitDefaults {
+		return fmt.Errorf("-init must be %q or %q, got %q", InitEmpty, InitDefaults, c.InitMode)
+	}
+	return nil
+}
+
+// findDefaultConfigFile looks for the default config file in the working directory, then next to the executable
+func findDefaultConfigFile() string {
+	candidates := []string{DefaultConfigFile}
+	if executable, err := os.Executable(); err == nil {
+		candidates = append(candidates, filepath.Join(filepath.Dir(executable), DefaultConfigFile))
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// firstNonEmpty returns the first value that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
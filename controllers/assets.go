@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"fmt"
+	"it_inventar/models"
+	"it_inventar/models/Ledger"
+	"it_inventar/views/console"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// handleItemAssets books pieces with serial numbers in or out, or shows and edits them, depending on the operation
+// chosen in the quantity menu. Items whose serial numbers are not tracked yet can start tracking them.
+func handleItemAssets(id int, operation string) {
+	item, err := store.GetItemByID(id)
+	if err != nil {
+		console.ShowError(err)
+		return
+	}
+	switch {
+	case operation == "1":
+		bookInAssets(item)
+	case operation == "2":
+		bookOutAssets(item)
+	case item.TracksAssets():
+		editAssets(item)
+	default:
+		startTrackingAssets(item)
+	}
+}
+
+// bookInAssets books pieces in by their serial numbers. Pieces of the item that were booked out come back,
+// new serial numbers are added with their purchase date.
+func bookInAssets(item models.Item) {
+	console.Clear()
+	if returnable := slices.DeleteFunc(slices.Clone(item.Assets), func(asset models.Asset) bool { return asset.Status == models.AssetInStock }); len(returnable) > 0 {
+		console.ShowMessage("Pieces that are not in stock:")
+		console.ShowAssets(returnable)
+	}
+	console.ShowMessage(fmt.Sprintf("Enter the serial numbers of the pieces of %s to book in, one per line. An empty line finishes:", item.ArticleName))
+
+	var returned []string
+	var added []models.Asset
+	entered := func(serialNumber string) bool {
+		return slices.ContainsFunc(returned, func(other string) bool { return models.SameSerialNumber(other, serialNumber) }) ||
+			slices.ContainsFunc(added, func(other models.Asset) bool { return models.SameSerialNumber(other.SerialNumber, serialNumber) })
+	}
+	for {
+		serialNumber := console.AskForInput()
+		if serialNumber == "" {
+			break
+		}
+		if entered(serialNumber) {
+			console.ShowMessage(fmt.Sprintf("⚠️ %s was already entered.", serialNumber))
+			continue
+		}
+		if index := slices.IndexFunc(item.Assets, func(asset models.Asset) bool { return models.SameSerialNumber(asset.SerialNumber, serialNumber) }); index >= 0 {
+			if item.Assets[index].Status == models.AssetInStock {
+				console.ShowMessage(fmt.Sprintf("⚠️ %s is already in stock.", serialNumber))
+				continue
+			}
+			if item.Assets[index].Status == models.AssetCheckedOut {
+				console.ShowMessage(fmt.Sprintf("⚠️ %s is checked out to %s, please check it in from the main menu.", serialNumber, item.Assets[index].Employee))
+				continue
+			}
+			returned = append(returned, item.Assets[index].SerialNumber)
+			console.ShowMessage(fmt.Sprintf("↩️ %s comes back into stock.", item.Assets[index].SerialNumber))
+			continue
+		}
+		if existing, found := models.FindSerialNumber(store.GetAllItems(), serialNumber, item.ID); found {
+			console.ShowMessage(fmt.Sprintf("❌ Serial number %s is already used by %s (ID %d).", serialNumber, existing.ArticleName, existing.ID))
+			continue
+		}
+		added = append(added, console.AskForNewAsset(serialNumber))
+		console.ShowMessage("Next serial number, or an empty line to finish:")
+	}
+
+	count := len(returned) + len(added)
+	if count == 0 {
+		console.ShowMessage("Nothing was booked in.")
+		return
+	}
+	// Pieces that only come back are most likely returns
+	reasons := Ledger.InReasons
+	if len(added) == 0 {
+		reasons = []string{Ledger.ReasonReturn, Ledger.ReasonPurchase, Ledger.ReasonCorrection}
+	}
+	booking := console.AskForMovement(reasons)
+	saveAssetChange(item.ID, fmt.Sprintf("book in %d pieces of %s", count, item.ArticleName), booking, func(current *models.Item) error {
+		if err := current.SetAssetStatus(returned, models.AssetInStock); err != nil {
+			return err
+		}
+		return current.AddAssets(added...)
+	})
+}
+
+// bookOutAssets books the chosen pieces in stock out
+func bookOutAssets(item models.Item) {
+	console.Clear()
+	inStock := item.AssetsWithStatus(models.AssetInStock)
+	if len(inStock) == 0 {
+		console.ShowMessage(fmt.Sprintf("⚠️ No piece of %s is in stock.", item.ArticleName))
+		return
+	}
+	console.ShowAssets(inStock)
+	console.ShowMessage("Enter the numbers or serial numbers of the pieces to book out (e.g. 1,3 or SN123):")
+
+	serialNumbers, err := chooseAssets(console.AskForInput(), inStock)
+	if err != nil {
+		console.ShowMessage(fmt.Sprintf("❌ %v", err))
+		return
+	}
+	booking := console.AskForMovement(Ledger.OutReasons)
+	saveAssetChange(item.ID, fmt.Sprintf("book out %d pieces of %s", len(serialNumbers), item.ArticleName), booking, func(current *models.Item) error {
+		// Another user may have booked them out in the meantime
+		for _, serialNumber := range serialNumbers {
+			if !slices.ContainsFunc(current.AssetsWithStatus(models.AssetInStock), func(asset models.Asset) bool { return asset.SerialNumber == serialNumber }) {
+				return fmt.Errorf("%s is no longer in stock", serialNumber)
+			}
+		}
+		return current.SetAssetStatus(serialNumbers, models.AssetBookedOut)
+	})
+}
+
+// chooseAssets returns the serial numbers of the pieces chosen by their number in the list or their serial number
+func chooseAssets(input string, assets []models.Asset) ([]string, error) {
+	var serialNumbers []string
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		index := slices.IndexFunc(assets, func(asset models.Asset) bool { return models.SameSerialNumber(asset.SerialNumber, part) })
+		if number, err := strconv.Atoi(part); index < 0 && err == nil && number >= 1 && number <= len(assets) {
+			index = number - 1
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("'%s' is not in the list", part)
+		}
+		if !slices.Contains(serialNumbers, assets[index].SerialNumber) {
+			serialNumbers = append(serialNumbers, assets[index].SerialNumber)
+		}
+	}
+	if len(serialNumbers) == 0 {
+		return nil, fmt.Errorf("no piece was chosen")
+	}
+	return serialNumbers, nil
+}
+
+// startTrackingAssets asks for the serial numbers of the pieces in stock, from then on the quantity of the item
+// is the number of its pieces in stock
+func startTrackingAssets(item models.Item) {
+	console.Clear()
+	if len(item.CheckedOut) > 0 {
+		console.ShowMessage(fmt.Sprintf("⚠️ Employees have pieces of %s, please check them in before tracking serial numbers.", item.ArticleName))
+		return
+	}
+	if item.Quantity == 0 {
+		bookInAssets(item)
+		return
+	}
+	console.ShowMessage(fmt.Sprintf("To track the serial numbers of %s, enter the serial numbers of the %d pieces in stock (or 'C' to cancel):", item.ArticleName, item.Quantity))
+
+	var assets []models.Asset
+	for len(assets) < item.Quantity {
+		console.ShowMessage(fmt.Sprintf("* Serial number of piece %d of %d:", len(assets)+1, item.Quantity))
+		serialNumber := console.AskForInput()
+		switch {
+		case strings.ToLower(serialNumber) == "c":
+			console.ShowMessage("Action canceled.")
+			return
+		case serialNumber == "":
+			continue
+		case slices.ContainsFunc(assets, func(asset models.Asset) bool { return models.SameSerialNumber(asset.SerialNumber, serialNumber) }):
+			console.ShowMessage(fmt.Sprintf("⚠️ %s was already entered.", serialNumber))
+			continue
+		}
+		if existing, found := models.FindSerialNumber(store.GetAllItems(), serialNumber, item.ID); found {
+			console.ShowMessage(fmt.Sprintf("❌ Serial number %s is already used by %s (ID %d).", serialNumber, existing.ArticleName, existing.ID))
+			continue
+		}
+		assets = append(assets, console.AskForNewAsset(serialNumber))
+	}
+
+	description := fmt.Sprintf("track serial numbers of %s", item.ArticleName)
+	saveAssetChange(item.ID, description, Ledger.Movement{Reason: Ledger.ReasonCorrection, Comment: description}, func(current *models.Item) error {
+		if current.TracksAssets() || current.Quantity != len(assets) {
+			return fmt.Errorf("the stock of %s was changed by another user, please try again", current.ArticleName)
+		}
+		return current.AddAssets(assets...)
+	})
+}
+
+// editAssets shows the pieces of the item and lets the user change the status, purchase date and notes of one
+func editAssets(item models.Item) {
+	console.Clear()
+	console.ShowAssets(item.Assets)
+	console.ShowMessage("Enter the number or serial number of the piece to edit (or press [Enter] to go back):")
+	input := console.AskForInput()
+	if input == "" {
+		return
+	}
+	serialNumbers, err := chooseAssets(input, item.Assets)
+	if err != nil || len(serialNumbers) != 1 {
+		console.ShowMessage("❌ Please choose exactly one piece from the list.")
+		return
+	}
+	index := slices.IndexFunc(item.Assets, func(asset models.Asset) bool { return asset.SerialNumber == serialNumbers[0] })
+	asset := console.AskForAssetDetails(item.Assets[index])
+	description := fmt.Sprintf("edit serial number %s of %s", asset.SerialNumber, item.ArticleName)
+	saveAssetChange(item.ID, description, Ledger.Movement{Reason: Ledger.ReasonCorrection, Comment: description}, func(current *models.Item) error {
+		return current.UpdateAsset(asset)
+	})
+}
+
+// saveAssetChange applies the change to the current state of the item, saves it with the booking in the ledger
+// and shows the new stock
+func saveAssetChange(id int, description string, booking Ledger.Movement, change func(current *models.Item) error) {
+	var saved models.Item
+	err := saveBooking(description, booking, func() error {
+		current, err := store.GetItemByID(id)
+		if err != nil {
+			return err
+		}
+		if err := change(&current); err != nil {
+			return err
+		}
+		saved = current
+		return store.UpdateItem(id, current)
+	})
+	if err != nil {
+		console.ShowMessage(fmt.Sprintf("❌ %v", err))
+		return
+	}
+	console.ShowMessage(fmt.Sprintf("New stock: %d pieces", saved.Quantity))
+	console.ShowMessage("✅ Serial numbers successfully updated!")
+}
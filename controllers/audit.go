@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"fmt"
+	"it_inventar/models/Audit"
+	"it_inventar/views/console"
+	"sort"
+	"strings"
+	"time"
+)
+
+// auditDateFormat is the format of the dates entered to filter the audit log
+const auditDateFormat = "02.01.2006"
+
+// auditLogFiles returns the data files whose audit logs are shown, files in the same directory share one log
+func auditLogFiles() []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, filePath := range backedUpFiles() {
+		if logPath := Audit.LogPath(filePath); !seen[logPath] {
+			seen[logPath] = true
+			files = append(files, filePath)
+		}
+	}
+	return files
+}
+
+// handleAuditLog asks for the filters and shows the matching entries of the audit log, newest first
+func handleAuditLog() {
+	filter := Audit.Filter{}
+	console.ShowMessage("Filter by item ID, category or supplier name (press [Enter] for all):")
+	filter.Key = console.GetUserInput()
+	console.ShowMessage("Filter by user (press [Enter] for all):")
+	filter.User = console.GetUserInput()
+	filter.From = askForAuditDate("From date (DD.MM.YYYY, press [Enter] for no limit):")
+	if to := askForAuditDate("To date (DD.MM.YYYY, press [Enter] for no limit):"); !to.IsZero() {
+		filter.To = to.AddDate(0, 0, 1).Add(-time.Nanosecond) // The whole day is included
+	}
+
+	var entries []Audit.Entry
+	for _, filePath := range auditLogFiles() {
+		fileEntries, err := Audit.ReadLog(filePath, filter)
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error reading the audit log: %v", err))
+			return
+		}
+		entries = append(entries, fileEntries...)
+	}
+	if len(entries) == 0 {
+		console.ShowMessage("⚠️ No audit log entries match the filter.")
+		return
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+
+	for page := console.InitialPage; ; page++ {
+		start, end := console.PageIndexCalculate(page, console.PageSize, len(entries))
+		console.ShowAuditEntries(entries[start:end])
+		if end == len(entries) {
+			console.ShowMessage(fmt.Sprintf("End of the audit log reached, %d entries shown.", len(entries)))
+			return
+		}
+		if strings.ToLower(console.GetPageInput()) == "c" {
+			return
+		}
+	}
+}
+
+// askForAuditDate reads a date for the audit log filter, an empty input means no limit
+func askForAuditDate(prompt string) time.Time {
+	for {
+		console.ShowMessage(prompt)
+		input := console.GetUserInput()
+		if input == "" {
+			return time.Time{}
+		}
+		date, err := time.ParseInLocation(auditDateFormat, input, time.Local)
+		if err == nil {
+			return date
+		}
+		console.ErrorMessage("Invalid date. Please use the format DD.MM.YYYY.")
+	}
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"fmt"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Storage"
+	"it_inventar/views/console"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backedUpFiles returns the data files that get a snapshot before every save
+func backedUpFiles() []string {
+	return []string{settings.DataPath(), settings.CategoriesPath(), settings.SupplierPath(), settings.AttributesPath(), settings.EmployeesPath(), settings.LocationsPath()}
+}
+
+// handleBackups lists the snapshots, previews the difference to the current data and restores a snapshot
+func handleBackups() {
+	for {
+		snapshots, err := Storage.ListSnapshots(backedUpFiles()...)
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error reading snapshots: %v", err))
+			return
+		}
+		if len(snapshots) == 0 {
+			console.ShowMessage("⚠️ No snapshots available yet. A snapshot is taken before every save.")
+			return
+		}
+		console.ShowSnapshotList(snapshots)
+
+		console.ShowMessage("Enter the number of the snapshot to preview and restore (or 'C' to cancel):")
+		input := console.GetUserInput()
+		if strings.ToLower(input) == "c" {
+			console.ShowMessage("Action canceled. Returning to the service menu...")
+			return
+		}
+		index, err := strconv.Atoi(input)
+		if err != nil || index < 1 || index > len(snapshots) {
+			console.ErrorMessage("Invalid input. Please enter a valid snapshot number.")
+			continue
+		}
+		snapshot := snapshots[index-1]
+		targetPath := snapshotTarget(snapshot)
+
+		diff, err := Storage.DiffFiles(targetPath, snapshot.Path)
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error comparing snapshot: %v", err))
+			continue
+		}
+		console.ShowSnapshotDiff(snapshot, diff)
+
+		console.ShowMessage(fmt.Sprintf("Restore this snapshot of %s? (y/n)", snapshot.FileName))
+		if strings.ToLower(console.GetUserInput()) != "y" {
+			console.ShowMessage("❌ Snapshot was not restored.")
+			continue
+		}
+		if err := Storage.RestoreSnapshot(snapshot, targetPath); err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error restoring snapshot: %v", err))
+			continue
+		}
+
+		restored := Audit.Entry{Operation: Audit.OpRestore, Entity: Audit.EntityFile, Key: snapshot.FileName, Field: "Snapshot", After: snapshot.Time.Format(time.RFC3339)}
+		if err := Audit.Record(targetPath, []Audit.Entry{restored}); err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ The snapshot was restored, but could not be written to the audit log: %v", err))
+		}
+
+		// The items are kept in memory, so they have to be loaded again from the restored file
+		if targetPath == settings.DataPath() {
+			if err := store.Initialize(); err != nil {
+				console.ShowError(err)
+			}
+			handleLoadReport()
+		}
+		console.ShowMessage(fmt.Sprintf("✅ %s restored from the snapshot of %s.", snapshot.FileName, snapshot.Time.Format("02.01.2006 / 15:04:05")))
+	}
+}
+
+// snapshotTarget returns the data file the snapshot was taken from
+func snapshotTarget(snapshot Storage.Snapshot) string {
+	for _, filePath := range backedUpFiles() {
+		if filepath.Base(filePath) == snapshot.FileName {
+			return filePath
+		}
+	}
+	return snapshot.FileName
+}
@@ -0,0 +1,403 @@
+package controllers
+
+import (
+	"fmt"
+	"it_inventar/models"
+	"it_inventar/models/Attribute"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Category"
+	"it_inventar/views/console"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// readCategoryTree reads the categories and returns them in tree order
+func readCategoryTree() ([]Category.Node, error) {
+	categories, err := Category.ReadCategoryRecords(settings.CategoriesPath())
+	if err != nil {
+		return nil, err
+	}
+	return Category.Tree(categories), nil
+}
+
+// itemsInCategories returns the items whose category is one of the passed categories
+func itemsInCategories(items []models.Item, categories []string) []models.Item {
+	var found []models.Item
+	for _, item := range items {
+		if slices.Contains(categories, item.Category) {
+			found = append(found, item)
+		}
+	}
+	return found
+}
+
+// numberPattern returns the pattern of the article numbers of the category, which may be inherited from a parent
+func numberPattern(category string) (models.NumberPattern, error) {
+	categories, err := Category.ReadCategoryRecords(settings.CategoriesPath())
+	if err != nil {
+		return models.NumberPattern{}, err
+	}
+	pattern, _ := Category.NumberPatternFor(categories, category)
+	return pattern, nil
+}
+
+// categoryAttributes returns the attributes of the category, including the ones inherited from its parents
+func categoryAttributes(nodes []Category.Node, category string) ([]Attribute.Definition, error) {
+	definitions, err := Attribute.ReadDefinitions(settings.AttributesPath())
+	if err != nil {
+		return nil, err
+	}
+	return Attribute.ForCategory(definitions, Category.Ancestors(nodes, category)), nil
+}
+
+// askForAttributes prompts for every attribute and returns the passed values with the answers.
+// Values of attributes that are not asked for are kept, the passed map is not changed.
+func askForAttributes(definitions []Attribute.Definition, values map[string]string) map[string]string {
+	answered := maps.Clone(values)
+	if answered == nil {
+		answered = map[string]string{}
+	}
+	for _, definition := range definitions {
+		if value := console.AskForAttribute(definition, answered[definition.Name]); value != "" {
+			answered[definition.Name] = value
+		} else {
+			delete(answered, definition.Name)
+		}
+	}
+	if len(answered) == 0 {
+		return nil
+	}
+	return answered
+}
+
+// filterAttributes returns the values of the passed attributes only
+func filterAttributes(values map[string]string, definitions []Attribute.Definition) map[string]string {
+	filtered := map[string]string{}
+	for _, definition := range definitions {
+		if value, ok := values[definition.Name]; ok {
+			filtered[definition.Name] = value
+		}
+	}
+	return filtered
+}
+
+// attributeSummary formats the values of the attributes for the review of an item, e.g. "Diagonal: 27, Panel: IPS"
+func attributeSummary(definitions []Attribute.Definition, values map[string]string) string {
+	var parts []string
+	for _, definition := range definitions {
+		if value := values[definition.Name]; value != "" {
+			parts = append(parts, fmt.Sprintf("%s: %s", definition.Name, value))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handleViewItemsByCategory shows the articles of one category, optionally together with its subcategories.
+// The attributes of the category are shown as columns and can be used as filters.
+func handleViewItemsByCategory() {
+	console.Clear()
+	categories, err := readCategoryTree()
+	if err != nil {
+		console.ShowError(err)
+		return
+	}
+	chosen := console.SelectCategory(categories, console.PageSize)
+	if chosen == "C" {
+		return
+	}
+
+	selected := []string{chosen}
+	if Category.HasChildren(categories, chosen) {
+		console.ShowMessage(fmt.Sprintf("Include the subcategories of '%s'? (y/n)", chosen))
+		if strings.ToLower(console.AskForInput()) == "y" {
+			selected = Category.Descendants(categories, chosen)
+		}
+	}
+
+	items := itemsInCategories(models.GetActiveItems(store.GetAllItems()), selected)
+	definitions, err := categoryAttributes(categories, chosen)
+	if err != nil {
+		console.ShowError(err)
+		return
+	}
+	var columns []string
+	for _, definition := range definitions {
+		columns = append(columns, definition.Name)
+	}
+
+	// Every filter narrows the items down further, until the user is done
+	for len(definitions) > 0 {
+		console.ShowAttributeFilterOptions(len(items), definitions)
+		input := console.AskForInput()
+		if input == "" {
+			break
+		}
+		index, err := strconv.Atoi(input)
+		if err != nil || index < 1 || index > len(definitions) {
+			console.MessageGeneralInvalidID()
+			continue
+		}
+		definition := definitions[index-1]
+		console.ShowMessage(fmt.Sprintf("Value of %s (numbers and dates can start with <, <=, > or >=):", definition.Name))
+		filter := console.AskForInput()
+		items = slices.DeleteFunc(items, func(item models.Item) bool {
+			return !definition.Matches(item.Attributes[definition.Name], filter)
+		})
+	}
+
+	console.HandleViewItemsGeneric(items, false, console.AttributeColumns(columns)...)
+	console.ShowExecuteCommandMenu()
+}
+
+// handleCategoryAttributes lets the user define the attributes the items of a category have
+func handleCategoryAttributes() {
+	for {
+		categories, err := readCategoryTree()
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error reading categories: %v", err))
+			return
+		}
+		if len(categories) == 0 {
+			console.ShowNoCategoriesMessage()
+			return
+		}
+		console.ShowCategoriesList(categories)
+
+		console.ShowMessage("Enter the number of the category whose attributes you want to edit (or 'C' to cancel):")
+		input := console.GetUserInput()
+		if strings.ToLower(input) == "c" {
+			console.ShowMessage("Action canceled. Returning to the service menu...")
+			return
+		}
+		index, err := strconv.Atoi(input)
+		if err != nil || index < 1 || index > len(categories) {
+			console.ErrorMessage("Invalid input. Please enter a valid category number.")
+			continue
+		}
+		editCategoryAttributes(categories, categories[index-1].CategoryName)
+	}
+}
+
+// editCategoryAttributes adds and deletes the attributes of one category until the user goes back
+func editCategoryAttributes(categories []Category.Node, category string) {
+	filePath := settings.AttributesPath()
+	for {
+		definitions, err := categoryAttributes(categories, category)
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error reading attributes: %v", err))
+			return
+		}
+		var inherited, own []Attribute.Definition
+		for _, definition := range definitions {
+			if definition.Category == category {
+				own = append(own, definition)
+			} else {
+				inherited = append(inherited, definition)
+			}
+		}
+		console.ShowAttributeDefinitions(category, inherited, own)
+
+		switch strings.ToLower(console.AskForAttributeAction()) {
+		case "a":
+			definition, ok := askForAttributeDefinition(category, definitions)
+			if !ok {
+				continue
+			}
+			err := saveUndoableListChange(fmt.Sprintf("add attribute %s to %s", definition.Name, category), Audit.EntityCategory, func() error {
+				return Attribute.AddDefinition(filePath, definition)
+			})
+			if err != nil {
+				console.ErrorMessage(fmt.Sprintf("❌ Error adding attribute: %v", err))
+				continue
+			}
+			console.ShowMessage(fmt.Sprintf("✅ Attribute '%s' added.", definition.Name))
+		case "d":
+			if len(own) == 0 {
+				console.ShowMessage("⚠️ The category has no attributes of its own.")
+				continue
+			}
+			console.ShowMessage("Enter the number of the attribute you want to delete:")
+			index, err := strconv.Atoi(console.GetUserInput())
+			if err != nil || index < 1 || index > len(own) {
+				console.ErrorMessage("Invalid input. Please enter a valid attribute number.")
+				continue
+			}
+			name := own[index-1].Name
+			err = saveUndoableListChange(fmt.Sprintf("delete attribute %s of %s", name, category), Audit.EntityCategory, func() error {
+				return Attribute.DeleteDefinition(filePath, category, name)
+			})
+			if err != nil {
+				console.ErrorMessage(fmt.Sprintf("❌ Error deleting attribute: %v", err))
+				continue
+			}
+			console.ShowMessage(fmt.Sprintf("✅ Attribute '%s' deleted, the values of the items are kept.", name))
+		case "c":
+			return
+		default:
+			console.ShowMessage("❌ Invalid selection. Please try again.")
+		}
+	}
+}
+
+// askForAttributeDefinition asks for the name, type, options and whether a new attribute is required.
+// The name must differ from the attributes the category already has, including the inherited ones.
+func askForAttributeDefinition(category string, existing []Attribute.Definition) (Attribute.Definition, bool) {
+	definition := Attribute.Definition{Category: category}
+
+	var names []string
+	for _, other := range existing {
+		names = append(names, other.Name)
+	}
+	console.ShowMessage("Enter the name of the attribute:")
+	name, err := settings.NameRules().Check(console.GetUserInput(), names)
+	if err != nil {
+		console.ShowMessage(fmt.Sprintf("❌ Invalid attribute name: %v.", err))
+		return definition, false
+	}
+	definition.Name = name
+
+	console.ShowAttributeTypes()
+	console.ShowMessage("Enter the number of the type:")
+	index, err := strconv.Atoi(console.GetUserInput())
+	if err != nil || index < 1 || index > len(Attribute.Types) {
+		console.ErrorMessage("Invalid input. Please enter a valid type number.")
+		return definition, false
+	}
+	definition.Type = Attribute.Types[index-1]
+
+	if definition.Type == Attribute.TypeChoice {
+		console.ShowMessage("Enter the options, separated by commas:")
+		for _, option := range strings.Split(console.GetUserInput(), ",") {
+			// The options are stored separated by "|", so it can't be part of one
+			option = strings.TrimSpace(strings.ReplaceAll(option, "|", ""))
+			if option != "" && !slices.Contains(definition.Options, option) {
+				definition.Options = append(definition.Options, option)
+			}
+		}
+		if len(definition.Options) == 0 {
+			console.ShowMessage("❌ A choice needs at least one option.")
+			return definition, false
+		}
+	}
+
+	console.ShowMessage("Is the attribute required? (y/n)")
+	definition.Required = strings.ToLower(console.GetUserInput()) == "y"
+	return definition, true
+}
+
+// handleMoveCategory puts a category below another one or back on the top level
+func handleMoveCategory() {
+	for {
+		categories, err := readCategoryTree()
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error reading categories: %v", err))
+			return
+		}
+		if len(categories) == 0 {
+			console.ShowNoCategoriesMessage()
+			return
+		}
+		console.ShowCategoriesList(categories)
+
+		console.ShowMessage("Enter the number of the category you want to move (or 'C' to cancel):")
+		input := console.GetUserInput()
+		if strings.ToLower(input) == "c" {
+			console.ShowMessage("Action canceled. Returning to the service menu...")
+			return
+		}
+		index, err := strconv.Atoi(input)
+		if err != nil || index < 1 || index > len(categories) {
+			console.ErrorMessage("Invalid input. Please enter a valid category number.")
+			continue
+		}
+		categoryName := categories[index-1].CategoryName
+
+		console.ShowMessage(fmt.Sprintf("Enter the number of the new parent of '%s', or press [Enter] to move it to the top level:", categoryName))
+		input = console.GetUserInput()
+		parent := ""
+		if input != "" {
+			index, err := strconv.Atoi(input)
+			if err != nil || index < 1 || index > len(categories) {
+				console.ErrorMessage("Invalid input. Please enter a valid category number.")
+				continue
+			}
+			parent = categories[index-1].CategoryName
+		}
+
+		place := "on the top level"
+		if parent != "" {
+			place = fmt.Sprintf("below %s", parent)
+		}
+		description := fmt.Sprintf("move category %s %s", categoryName, place)
+		err = saveUndoableListChange(description, Audit.EntityCategory, func() error {
+			return Category.MoveCategory(settings.CategoriesPath(), categoryName, parent)
+		})
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error moving category: %v", err))
+			continue
+		}
+		console.ShowMessage(fmt.Sprintf("✅ '%s' is now %s.", categoryName, place))
+	}
+}
+
+// handleNumberPatterns lets the user define the prefix, the width and the check digit of the article numbers of a category
+func handleNumberPatterns() {
+	for {
+		records, err := Category.ReadCategoryRecords(settings.CategoriesPath())
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error reading categories: %v", err))
+			return
+		}
+		categories := Category.Tree(records)
+		if len(categories) == 0 {
+			console.ShowNoCategoriesMessage()
+			return
+		}
+		console.ShowCategoriesList(categories)
+
+		console.ShowMessage("Enter the number of the category whose article numbers you want to define (or 'C' to cancel):")
+		input := console.GetUserInput()
+		if strings.ToLower(input) == "c" {
+			console.ShowMessage("Action canceled. Returning to the service menu...")
+			return
+		}
+		index, err := strconv.Atoi(input)
+		if err != nil || index < 1 || index > len(categories) {
+			console.ErrorMessage("Invalid input. Please enter a valid category number.")
+			continue
+		}
+		categoryName := categories[index-1].CategoryName
+
+		pattern, definedIn := Category.NumberPatternFor(records, categoryName)
+		switch {
+		case definedIn == "":
+			console.ShowMessage(fmt.Sprintf("'%s' has no number pattern, any article number is accepted.", categoryName))
+		case definedIn != categoryName:
+			console.ShowMessage(fmt.Sprintf("'%s' uses the pattern %s of '%s', a pattern of its own replaces it.", categoryName, pattern, definedIn))
+			pattern = models.NumberPattern{}
+		default:
+			console.ShowMessage(fmt.Sprintf("Current pattern of '%s': %s", categoryName, pattern))
+		}
+
+		pattern = console.AskForNumberPattern(pattern)
+		err = saveUndoableListChange(fmt.Sprintf("set number pattern of category %s", categoryName), Audit.EntityCategory, func() error {
+			return Category.SetNumberPattern(settings.CategoriesPath(), categoryName, pattern)
+		})
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error saving the number pattern: %v", err))
+			continue
+		}
+		if !pattern.IsSet() {
+			console.ShowMessage(fmt.Sprintf("✅ '%s' has no number pattern of its own.", categoryName))
+			continue
+		}
+		next, err := pattern.Next(store.GetAllItems())
+		if err != nil {
+			console.ShowMessage(fmt.Sprintf("⚠️ %v.", err))
+			continue
+		}
+		console.ShowMessage(fmt.Sprintf("✅ Article numbers of '%s': %s, the next free one is %s.", categoryName, pattern, next))
+	}
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"errors"
+	"it_inventar/models"
+	"it_inventar/views/console"
+	"strings"
+)
+
+// errChangeDiscarded is returned when the user discards a change after another user saved the inventory
+var errChangeDiscarded = errors.New("your change was discarded, the inventory was reloaded")
+
+// saveChange runs the passed change. When another user saved the inventory in the meantime, the user can
+// reload it and apply the change again on top of the current data, or discard the change.
+// The change has to read the items it works on from the store, so it merges into the reloaded data.
+func saveChange(change func() error) error {
+	for {
+		err := change()
+		if errors.Is(err, models.ErrSaveBlocked) {
+			if !handleLoadReport() {
+				return err
+			}
+			continue
+		}
+		if !errors.Is(err, models.ErrStaleData) {
+			return err
+		}
+
+		choice := strings.ToLower(console.AskForStaleDataResolution())
+		if reloadErr := store.Initialize(); reloadErr != nil {
+			return reloadErr
+		}
+		if choice != "r" {
+			return errChangeDiscarded
+		}
+	}
+}
+
+// handleLoadReport warns about rows that could not be loaded and asks the user to acknowledge them.
+// It returns true when saving is allowed.
+func handleLoadReport() bool {
+	report := store.LoadReport()
+	if report.Acknowledged {
+		return true
+	}
+
+	console.ShowLoadIssues(report)
+	if strings.ToLower(console.AskForLoadIssuesAcknowledgement()) != "y" {
+		console.ShowMessage("⚠️ Saving stays blocked until the skipped rows are acknowledged.")
+		console.ShowContinue()
+		return false
+	}
+	store.AcknowledgeLoadReport()
+	return true
+}
@@ -2,8 +2,12 @@ package controllers
 
 import (
 	"fmt"
+	"it_inventar/config"
 	"it_inventar/models"
+	"it_inventar/models/Audit"
 	"it_inventar/models/Category"
+	"it_inventar/models/Ledger"
+	"it_inventar/models/Storage"
 	"it_inventar/models/Supplier"
 	"it_inventar/views/console"
 	"strconv"
@@ -12,15 +16,32 @@ import (
 
 const (
 	InitialPage = console.InitialPage
-	PageSize    = console.PageSize
 
 	messageInvalidInput         = "Invalid input!"
 	messageInvalidInputTryAgain = "Please choose:\n[y] Confirm\n[n] Cancel."
 )
 
-// Run does the running of the console application
-func Run() {
-	console.CheckAndHandleError(models.Initialize())
+// store is the item storage the handlers work on, it is injected by Run
+var store models.ItemStore
+
+// settings are the configured file paths and options, they are injected by Run
+var settings config.Config
+
+// Run does the running of the console application with the passed settings on top of the passed item store
+func Run(appSettings config.Config, itemStore models.ItemStore) {
+	settings = appSettings
+	store = itemStore
+	console.PageSize = settings.PageSize
+	console.BelowMinimum = itemsBelowMinimum
+	Storage.Backups = settings.BackupPolicy()
+	if !handleFirstRun() {
+		console.ShowGoodbye()
+		console.ShutDownNormal()
+	}
+	console.CheckAndHandleError(store.Initialize())
+	handleLoadReport()
+	applyRetentionPolicy()
+	handleReferenceCheck()
 	console.Clear()
 	console.ShowExecuteCommandMenu()
 
@@ -38,28 +59,52 @@ func handleAddItem() {
 	var isEditing bool = false
 	var articleName, chosenCategory, articleNumber, chosenSupplier, notes string
 	var quantity int
+	var attributes map[string]string
+	var stockLevel models.StockLevel
 
-	selectedCategories, err := Category.ReadCategories(models.FileCategories)
+	categoryTree, err := readCategoryTree()
 	if err != nil {
 		console.ShowError(err)
 		return
 	}
-	selectedSuppliers, err := Supplier.ReadSuppliers(models.FileSupplier)
+	selectedSuppliers, err := Supplier.ReadSuppliers(settings.SupplierPath())
 	if err != nil {
 		console.ShowError(err)
 		return
 	}
+	// A new inventory starts without suppliers, the items can't be added before one exists
+	if len(selectedSuppliers) == 0 {
+		showMissingSupplierMessage()
+		return
+	}
 
 	for {
 		articleName = console.AskForArticleName(articleName, isEditing)
 		console.Clear()
-		chosenCategory = console.HandleAddSelectItem(chosenCategory, selectedCategories, "Category", isEditing)
+		chosenCategory = console.HandleAddSelectCategory(chosenCategory, categoryTree, isEditing)
 		if chosenCategory == "C" {
 			console.InputC()
 			return
 		}
 		console.Clear()
-		articleNumber = console.AskForArticleNumber(articleNumber, isEditing)
+		definitions, err := categoryAttributes(categoryTree, chosenCategory)
+		if err != nil {
+			console.ShowError(err)
+			return
+		}
+		// Only the attributes of the chosen category are kept, in case it was changed during the correction
+		attributes = askForAttributes(definitions, filterAttributes(attributes, definitions))
+		console.Clear()
+		number, editID, ok := askForArticleNumber(articleNumber, isEditing, 0, chosenCategory)
+		if !ok {
+			console.InputC()
+			return
+		}
+		if editID != 0 {
+			editExistingItem(editID)
+			return
+		}
+		articleNumber = number
 		console.Clear()
 		chosenSupplier = console.HandleAddSelectItem(chosenSupplier, selectedSuppliers, "Supplier", isEditing)
 		if chosenSupplier == "C" {
@@ -67,11 +112,14 @@ func handleAddItem() {
 			return
 		}
 		console.Clear()
+		showSupplierDetails(chosenSupplier)
 		quantity = console.AskForQuantity(quantity, isEditing)
 		console.Clear()
 		notes = console.AskForNotes(notes, isEditing)
+		stockLevel = console.AskForStockLevel(stockLevel, categoryStockLevel(chosenCategory))
 
-		confirmed, exit := handleConfirmItemDetails(articleName, chosenCategory, articleNumber, chosenSupplier, quantity, notes)
+		confirmed, exit := handleConfirmItemDetails(articleName, chosenCategory, articleNumber, chosenSupplier, quantity, notes,
+			attributeSummary(definitions, attributes), stockLevelSummary(stockLevel, chosenCategory))
 		if exit {
 			return
 		}
@@ -84,8 +132,13 @@ func handleAddItem() {
 				Supplier:      chosenSupplier,
 				Quantity:      quantity,
 				Note:          notes,
+				Attributes:    attributes,
+				StockLevel:    stockLevel,
 			}
-			err := models.AddItem(data)
+			err := saveUndoableItemChange(fmt.Sprintf("add %s", articleName), func() error {
+				_, err := store.AddItem(data)
+				return err
+			})
 			if err != nil {
 				console.ShowError(err)
 			} else {
@@ -104,21 +157,21 @@ func handleAddItem() {
 // handleRemoveItem Handles the removal of an item from the inventory.
 func handleRemoveItem() {
 	console.Clear()
-	items := models.GetAllItems() // Get all items from the inventory
+	items := store.GetAllItems() // Get all items from the inventory
 
-	if console.ChecksInventory() { // Checks inventory for content
+	if console.ChecksInventory(items) { // Checks inventory for content
 		return
 	}
 
 	page := InitialPage
 	for {
-		start, end := console.PageIndexCalculate(page, PageSize, len(items))
+		start, end := console.PageIndexCalculate(page, console.PageSize, len(items))
 
-		console.ShowAllItems(items[start:end], start, false) // showDeletedDate = false
+		console.ShowAllItems(items[start:end], false) // showDeletedDate = false
 
 		choice := console.PageIndexPrompt("Item")
 
-		exit, item, rowId := console.PageIndexUserInput(choice, &page, end, items)
+		exit, item, id := console.PageIndexUserInput(choice, &page, end, items)
 		if exit {
 			return
 		}
@@ -129,7 +182,9 @@ func handleRemoveItem() {
 				choice = console.AskForInput()
 
 				if strings.ToLower(choice) == "y" {
-					err := models.RemoveItem(rowId)
+					err := saveUndoableItemChange(fmt.Sprintf("delete %s", item.ArticleName), func() error {
+						return store.RemoveItem(id)
+					})
 					if err != nil {
 						console.ShowError(err)
 					} else {
@@ -158,23 +213,23 @@ func handleRemoveItem() {
 // handleChangeQuantity edits an item in the inventory
 func handleChangeQuantity() {
 	console.Clear()
-	items := models.GetAllItems()
+	items := store.GetAllItems()
 
 	activeItems := models.GetActiveItems(items)
 
-	if console.ChecksInventory() {
+	if console.ChecksInventory(items) {
 		return
 	}
 
 	page := InitialPage
 	for {
-		start, end := console.PageIndexCalculate(page, PageSize, len(activeItems))
+		start, end := console.PageIndexCalculate(page, console.PageSize, len(activeItems))
 
-		console.ShowAllItems(activeItems[start:end], start, false) // showDeletedDate = false
+		console.ShowAllItems(activeItems[start:end], false) // showDeletedDate = false
 
 		choice := console.PageIndexPrompt("Item")
 
-		exit, item, rowId := console.PageIndexUserInput(choice, &page, end, activeItems) // Verwendung von activeItems
+		exit, item, id := console.PageIndexUserInput(choice, &page, end, activeItems) // Verwendung von activeItems
 		if exit {
 			return
 		}
@@ -184,19 +239,49 @@ func handleChangeQuantity() {
 			for {
 				choice = console.AskForInput()
 				if strings.ToLower(choice) == "y" {
-					// Ask for adding or subtracting
-					console.ShowMessage(fmt.Sprintf("[1] Add\n[2] Subtract"))
+					// Ask for adding or subtracting, pieces with serial numbers are booked one by one
+					console.ShowQuantityOperations()
 					operation := console.AskForInput()
+					if operation == "1" && !item.TracksAssets() && item.Quantity == 0 {
+						console.ShowMessage("Track the serial numbers of the new pieces? (y/n)")
+						if strings.ToLower(console.AskForInput()) == "y" {
+							operation = "3"
+						}
+					}
+					if operation == "3" || operation == "4" || operation == "5" || (item.TracksAssets() && (operation == "1" || operation == "2")) {
+						switch operation {
+						case "4":
+							handleTransferStock(id)
+						case "5":
+							handleMovementHistory(id)
+						default:
+							handleItemAssets(id, operation)
+						}
+						if current, err := store.GetItemByID(id); err == nil {
+							warnBelowMinimum(current, item.Quantity)
+						}
+						console.ShowContinue()
+						console.Clear()
+						console.ShowExecuteCommandMenu()
+						return
+					}
 
+					var delta int
+					location, ok := models.NoLocation, true
+					var booking Ledger.Movement
 					if strings.ToLower(operation) == "1" {
-						// Ask for the quantity to add
+						// Ask for the quantity to add and where the pieces are put
 						console.Clear()
 						console.ShowMessage(fmt.Sprintf("Current stock: %d pieces", item.Quantity))
 						console.ShowMessage("Enter the quantity to add:")
-						quantityToAdd := console.AskForQuantity(0, false)
-						item.Quantity += quantityToAdd
+						delta = console.AskForQuantity(0, false)
+						if location, ok = askForStockLocation(*item, false); !ok {
+							console.HandleChancelAction()
+							return
+						}
+						booking = console.AskForMovement(Ledger.InReasons)
 					} else if strings.ToLower(operation) == "2" {
-						// Ask for the quantity to subtract
+						// Ask for the quantity to subtract and where the pieces are taken from
 						console.Clear()
 						console.ShowMessage(fmt.Sprintf("Current stock: %d pieces", item.Quantity))
 						console.ShowMessage("Enter the quantity to subtract:")
@@ -206,21 +291,55 @@ func handleChangeQuantity() {
 							console.ShowContinue()
 							return // Funktion abbrechen, wenn die Menge nach dem Subtrahieren weniger als 0 ist
 						}
-						item.Quantity -= quantityToSubtract
+						if location, ok = askForStockLocation(*item, true); !ok {
+							console.HandleChancelAction()
+							return
+						}
+						booking = console.AskForMovement(Ledger.OutReasons)
+						delta = -quantityToSubtract
 					} else {
-						console.ShowMessage("❌ Invalid selection. Please choose '1' or '2'.")
+						console.ShowMessage("❌ Invalid selection. Please choose '1', '2', '3', '4' or '5'.")
 						console.ShowContinue()
 						continue
 					}
 
-					// Update item quantity
-					err := models.UpdateItem(rowId-1, *item)
+					// Update item quantity, the booking is applied to the current stock in case it was reloaded
+					booking.Location = location
+					before := item.Quantity
+					err := saveBooking(fmt.Sprintf("book %+d pieces of %s", delta, item.ArticleName), booking, func() error {
+						current, err := store.GetItemByID(id)
+						if err != nil {
+							return err
+						}
+						before = current.Quantity
+						if current.Quantity+delta < 0 {
+							return fmt.Errorf("the quantity to subtract exceeds the available quantity of %d pieces", current.Quantity)
+						}
+						if current.LocationQuantity(location) < -delta {
+							return fmt.Errorf("only %d pieces are at %s", current.LocationQuantity(location), models.LocationName(location))
+						}
+						// A lower quantity takes the pieces without location first, so the chosen pieces are moved there before
+						if delta < 0 && location != models.NoLocation {
+							if err := current.Transfer(location, models.NoLocation, -delta); err != nil {
+								return err
+							}
+						}
+						current.Quantity += delta
+						if delta > 0 && location != models.NoLocation {
+							if err := current.Transfer(models.NoLocation, location, delta); err != nil {
+								return err
+							}
+						}
+						*item = current
+						return store.UpdateItem(id, current)
+					})
 					if err != nil {
 						console.ShowError(err)
 					} else {
 						console.Clear()
 						console.ShowMessage(fmt.Sprintf("New stock: %d pieces", item.Quantity))
 						console.ShowMessage("✅ Item quantity successfully updated!")
+						warnBelowMinimum(*item, before)
 						console.ShowContinue()
 						console.Clear()
 						console.ShowExecuteCommandMenu()
@@ -246,108 +365,226 @@ func handleChangeQuantity() {
 // handleChanceArticleInformation edits an item in the inventory
 func handleChanceArticleInformation() {
 	console.Clear()
-	items := models.GetAllItems()
+	items := store.GetAllItems()
 	activeItems := models.GetActiveItems(items)
 
-	if console.ChecksInventory() {
+	if console.ChecksInventory(items) {
 		return
 	}
 
 	page := InitialPage
 	for {
-		var isEditing bool = false
-		var NewArticleName, newCategory, newArticleNumber, newSupplier, newNotes string
-
-		start, end := console.PageIndexCalculate(page, PageSize, len(activeItems))
+		start, end := console.PageIndexCalculate(page, console.PageSize, len(activeItems))
 
-		console.ShowAllItems(activeItems[start:end], start, false) // showDeletedDate = false
+		console.ShowAllItems(activeItems[start:end], false) // showDeletedDate = false
 
 		choice := console.PageIndexPrompt("Item")
 
-		exit, item, rowId := console.PageIndexUserInput(choice, &page, end, activeItems)
+		exit, item, id := console.PageIndexUserInput(choice, &page, end, activeItems)
 		if exit {
 			return
 		}
 		if item != nil {
-			for {
-				console.Clear()
-				// The input values are now initialized only once and reused for corrections
-				console.ShowMessage(fmt.Sprintf("Current item name: %s", item.ArticleName))
-				NewArticleName = console.AskForArticleName(item.ArticleName, isEditing)
+			editItem(*item, id)
+			return
+		}
+	}
+}
 
-				// Load categories and suppliers
-				selectedCategories, err := Category.ReadCategories(models.FileCategories)
-				if err != nil {
-					console.ShowError(err)
-					return
-				}
-				selectedSuppliers, err := Supplier.ReadSuppliers(models.FileSupplier)
+// editItem asks for the new values of the item with the passed ID until they are saved or the user cancels
+func editItem(item models.Item, id int) {
+	var isEditing bool = false
+	var NewArticleName, newCategory, newArticleNumber, newSupplier, newNotes string
+	var newAttributes map[string]string
+	newStockLevel := item.StockLevel
+
+	for {
+		console.Clear()
+		// The input values are now initialized only once and reused for corrections
+		console.ShowMessage(fmt.Sprintf("Current item name: %s", item.ArticleName))
+		NewArticleName = console.AskForArticleName(item.ArticleName, isEditing)
+
+		// Load categories and suppliers
+		categoryTree, err := readCategoryTree()
+		if err != nil {
+			console.ShowError(err)
+			return
+		}
+		selectedSuppliers, err := Supplier.ReadSuppliers(settings.SupplierPath())
+		if err != nil {
+			console.ShowError(err)
+			return
+		}
+		if len(selectedSuppliers) == 0 {
+			showMissingSupplierMessage()
+			return
+		}
+		console.Clear()
+		// Select category
+		console.ShowMessage(fmt.Sprintf("Current category: %s", item.Category))
+		newCategory = console.HandleAddSelectCategory(newCategory, categoryTree, isEditing)
+		if newCategory == "C" {
+			return
+		}
+		console.Clear()
+		definitions, err := categoryAttributes(categoryTree, newCategory)
+		if err != nil {
+			console.ShowError(err)
+			return
+		}
+		if newAttributes == nil {
+			newAttributes = item.Attributes
+		}
+		// Only the attributes of the chosen category are kept, the values of the old category are dropped
+		newAttributes = askForAttributes(definitions, filterAttributes(newAttributes, definitions))
+		console.Clear()
+		console.ShowMessage(fmt.Sprintf("Current article number: %s", item.ArticleNumber))
+		number, editID, ok := askForArticleNumber(item.ArticleNumber, isEditing, id, newCategory)
+		if !ok {
+			console.InputC()
+			return
+		}
+		if editID != 0 {
+			editExistingItem(editID)
+			return
+		}
+		newArticleNumber = number
+		console.Clear()
+		// Select supplier
+		console.ShowMessage(fmt.Sprintf("Current supplier: %s", item.Supplier))
+		newSupplier = console.HandleAddSelectItem(newSupplier, selectedSuppliers, "Supplier", isEditing)
+		if newSupplier == "C" {
+			return
+		}
+
+		console.Clear()
+		showSupplierDetails(newSupplier)
+		newQuantity := item.Quantity
+
+		console.ShowMessage(fmt.Sprintf("Current notes: %s", item.Note))
+		newNotes = console.AskForNotes(item.Note, isEditing)
+		newStockLevel = console.AskForStockLevel(newStockLevel, categoryStockLevel(newCategory))
+
+		// Confirmation to edit the item
+		confirmed, exit := handleConfirmItemDetails(NewArticleName, newCategory, newArticleNumber, newSupplier, newQuantity, newNotes,
+			attributeSummary(definitions, newAttributes), stockLevelSummary(newStockLevel, newCategory))
+		if exit {
+			return // Beenden, wenn "c" gewählt wurde
+		}
+
+		if confirmed {
+			// Update item
+			// The edited fields are applied to the current item in case it was reloaded
+			err := saveUndoableItemChange(fmt.Sprintf("edit %s", item.ArticleName), func() error {
+				current, err := store.GetItemByID(id)
 				if err != nil {
-					console.ShowError(err)
-					return
+					return err
 				}
+				current.ArticleName = NewArticleName
+				current.Category = newCategory
+				current.ArticleNumber = newArticleNumber
+				current.Supplier = newSupplier
+				current.Note = newNotes
+				current.Attributes = newAttributes
+				current.StockLevel = newStockLevel
+				return store.UpdateItem(id, current)
+			})
+			if err != nil {
+				console.ShowError(err)
+			} else {
+				console.ShowMessage("✅ Item successfully updated!")
+				console.ShowContinue()
 				console.Clear()
-				// Select category
-				console.ShowMessage(fmt.Sprintf("Current category: %s", item.Category))
-				newCategory = console.HandleAddSelectItem(newCategory, selectedCategories, "Category", isEditing)
-				if newCategory == "C" {
-					return
-				}
-				console.Clear()
-				console.ShowMessage(fmt.Sprintf("Current article number: %s", item.ArticleNumber))
-				newArticleNumber = console.AskForArticleNumber(item.ArticleNumber, isEditing)
-				console.Clear()
-				// Select supplier
-				console.ShowMessage(fmt.Sprintf("Current supplier: %s", item.Supplier))
-				newSupplier = console.HandleAddSelectItem(newSupplier, selectedSuppliers, "Supplier", isEditing)
-				if newSupplier == "C" {
-					return
-				}
+				console.ShowExecuteCommandMenu()
+				return
+			}
+		} else {
+			isEditing = true
+			console.ShowMessage("Please make the necessary changes.")
+		}
+	}
+}
 
-				console.Clear()
-				newQuantity := item.Quantity
+// showMissingSupplierMessage tells the user to create a supplier before items can be added or edited
+func showMissingSupplierMessage() {
+	console.Clear()
+	console.ShowExecuteCommandMenu()
+	console.ShowMessage("⚠️ There are no suppliers yet. Please create a supplier in the service menu first.")
+}
 
-				console.ShowMessage(fmt.Sprintf("Current notes: %s", item.Note))
-				newNotes = console.AskForNotes(item.Note, isEditing)
+// editExistingItem opens the item with the passed ID for editing, it is used to switch to the item that has a number
+func editExistingItem(id int) {
+	existing, err := store.GetItemByID(id)
+	if err != nil {
+		console.ShowError(err)
+		return
+	}
+	editItem(existing, id)
+}
 
-				// Confirmation to edit the item
-				confirmed, exit := handleConfirmItemDetails(NewArticleName, newCategory, newArticleNumber, newSupplier, newQuantity, newNotes)
-				if exit {
-					return // Beenden, wenn "c" gewählt wurde
-				}
+// askForArticleNumber asks for the article number until it matches the number pattern of the category and no item
+// other than excludeID has it. With a pattern, the next free number is proposed. When the number is taken, the item
+// that has it is shown and the user can enter another number, edit that item instead or cancel.
+// It returns the number or the ID of the item to edit instead, ok is false when the user canceled.
+func askForArticleNumber(defaultValue string, isEditing bool, excludeID int, category string) (number string, editID int, ok bool) {
+	// The number the item already has is kept even if another item shares it, like the store does.
+	// It only has to match the pattern when the item moves to another category.
+	var ownNumber string
+	if own, err := store.GetItemByID(excludeID); err == nil {
+		ownNumber = own.ArticleNumber
+		if own.Category != category {
+			ownNumber = ""
+		}
+	}
 
-				if confirmed {
-					// Update item
-					data := models.Item{
-						ArticleName:   NewArticleName,
-						Category:      newCategory,
-						ArticleNumber: newArticleNumber,
-						Supplier:      newSupplier,
-						Quantity:      newQuantity,
-						Note:          newNotes,
-					}
-					// Adjust the index correctly here
-					err := models.UpdateItem(rowId-1, data)
-					if err != nil {
-						console.ShowError(err)
-					} else {
-						console.ShowMessage("✅ Item successfully updated!")
-						console.ShowContinue()
-						console.Clear()
-						console.ShowExecuteCommandMenu()
-						return
-					}
-				} else {
-					isEditing = true
-					console.ShowMessage("Please make the necessary changes.")
-				}
+	pattern, err := numberPattern(category)
+	if err != nil {
+		console.ShowError(err)
+	}
+	keepsOwn := ownNumber != "" && models.SameArticleNumber(defaultValue, ownNumber)
+	if pattern.IsSet() && !keepsOwn && pattern.Check(defaultValue) != nil {
+		if next, err := pattern.Next(store.GetAllItems()); err != nil {
+			console.ShowMessage(fmt.Sprintf("⚠️ %v.", err))
+		} else {
+			console.ShowMessage(fmt.Sprintf("Article numbers of %s: %s, press [Enter] to use the next free number %s.", category, pattern, next))
+			defaultValue, isEditing = next, true
+		}
+	}
+
+	for {
+		number = console.AskForArticleNumber(defaultValue, isEditing)
+		if ownNumber != "" && models.SameArticleNumber(number, ownNumber) {
+			return number, 0, true
+		}
+		if pattern.IsSet() {
+			if err := pattern.Check(number); err != nil {
+				console.ShowMessage(fmt.Sprintf("❌ Invalid article number: %v.", err))
+				continue
 			}
 		}
+		existing, taken := models.FindArticleNumber(store.GetAllItems(), number, excludeID, settings.UniqueNumbersIncludeDeleted)
+		if !taken {
+			return number, 0, true
+		}
+
+		console.ShowArticleNumberConflict(number, existing)
+		switch strings.ToLower(console.AskForArticleNumberConflictResolution(!existing.IsDeleted)) {
+		case "e":
+			if !existing.IsDeleted {
+				return "", existing.ID, true
+			}
+		case "c":
+			return "", 0, false
+		}
+		// The taken number is not offered as the default again
+		if models.SameArticleNumber(defaultValue, number) {
+			defaultValue, isEditing = "", false
+		}
 	}
 }
 
 // handleConfirmItemDetails is a method that is used to obtain confirmation from the user for the specified item details
-func handleConfirmItemDetails(articleName, category, articleNumber, supplier string, quantity int, notes string) (bool, bool) {
+func handleConfirmItemDetails(articleName, category, articleNumber, supplier string, quantity int, notes, attributes, stockLevel string) (bool, bool) {
 	console.Clear()
 	console.ShowMessage("Please review the new data:")
 	console.ShowMessage(fmt.Sprintf("Item name: %s", articleName))
@@ -356,6 +593,12 @@ func handleConfirmItemDetails(articleName, category, articleNumber, supplier str
 	console.ShowMessage(fmt.Sprintf("Supplier: %s", supplier))
 	console.ShowMessage(fmt.Sprintf("Quantity: %d", quantity))
 	console.ShowMessage(fmt.Sprintf("Notes: %s", notes))
+	if attributes != "" {
+		console.ShowMessage(fmt.Sprintf("Attributes: %s", attributes))
+	}
+	if stockLevel != "" {
+		console.ShowMessage(fmt.Sprintf("Stock level: %s", stockLevel))
+	}
 	console.ShowMessage("\nAre the details correct? (y/n) or [c] to return to the main menu.")
 
 	choice := console.AskForInput()
@@ -370,7 +613,7 @@ func handleConfirmItemDetails(articleName, category, articleNumber, supplier str
 		return false, true
 	default:
 		console.ShowMessage("Invalid input, please try again.")
-		return handleConfirmItemDetails(articleName, category, articleNumber, supplier, quantity, notes)
+		return handleConfirmItemDetails(articleName, category, articleNumber, supplier, quantity, notes, attributes, stockLevel)
 	}
 }
 
@@ -378,28 +621,21 @@ func handleConfirmItemDetails(articleName, category, articleNumber, supplier str
 // *handleViewItems Shows all items that have not been deleted.
 // *handleViewItems: Zeigt alle Gegenstände die nicht gelöscht sind.
 func handleViewItems() {
-	activeItems := models.GetActiveItems(models.GetAllItems())
-	console.HandleViewItemsGeneric(activeItems, false)
-}
-
-// *handleViewDeletedItems: Shows all items that have been deleted
-// *handleViewDeletedItems: Zeigt alle Gegenstände die gelöscht sind
-func handleViewDeletedItems() {
-	deletedItems := models.GetDeletedItems(models.GetAllItems())
-	console.HandleViewItemsGeneric(deletedItems, true)
+	activeItems, columns := filterByLocation(models.GetActiveItems(store.GetAllItems()))
+	console.HandleViewItemsGeneric(activeItems, false, columns...)
 }
 
 // *handleViewAllItems: Shows all deleted and undeleted items
 // *handleViewAllItems: Zeigt alle gelöschte und nicht gelöschte Gegenstände
 func handleViewAllItems() {
-	items := models.GetAllItems()
+	items := store.GetAllItems()
 	console.HandleViewItemsGeneric(items, true)
 }
 
 // handleShowSuppliers displays a list of suppliers and allows navigation or exiting
 func handleShowSuppliers() {
 	// Read the list of suppliers from the CSV file
-	suppliers, err := Supplier.ReadSuppliers("supplier.csv")
+	suppliers, err := Supplier.ReadSuppliers(settings.SupplierPath())
 	if err != nil {
 		console.ErrorMessage(fmt.Sprintf("❌ Error reading suppliers: %v\n", err))
 		return
@@ -413,11 +649,7 @@ func handleShowSuppliers() {
 	page := InitialPage
 	for {
 		// Calculate the start and end indices for the current page
-		start := page * PageSize
-		end := start + PageSize
-		if end > len(suppliers) {
-			end = len(suppliers)
-		}
+		start, end := console.PageIndexCalculate(page, console.PageSize, len(suppliers))
 
 		// Display the current page of suppliers
 		console.DisplaySuppliers(suppliers, start, end)
@@ -448,7 +680,7 @@ func handleShowSuppliers() {
 
 // handleAddSuppliers allows the user to add new suppliers to the list with an option to cancel
 func handleAddSuppliers() {
-	filePath := "supplier.csv"
+	filePath := settings.SupplierPath()
 
 	for {
 		// Display the list of existing suppliers
@@ -469,13 +701,16 @@ func handleAddSuppliers() {
 		}
 
 		// Validate the supplier name
-		if !Supplier.IsValidSupplierName(supplierName) {
-			console.ShowMessage("❌ Invalid supplier name. Please use only letters, numbers, and spaces, and ensure it is not empty.")
+		supplierName, err = settings.NameRules().Check(supplierName, suppliers)
+		if err != nil {
+			console.ShowMessage(fmt.Sprintf("❌ Invalid supplier name: %v.", err))
 			continue
 		}
 
 		// Add the new supplier to the file
-		err = Supplier.AddSupplierToFile(filePath, supplierName)
+		err = saveUndoableListChange(fmt.Sprintf("add supplier %s", supplierName), Audit.EntitySupplier, func() error {
+			return Supplier.AddSupplierToFile(filePath, supplierName)
+		})
 		if err != nil {
 			console.ErrorMessage(fmt.Sprintf("❌ Error adding supplier: %v", err))
 		} else {
@@ -486,7 +721,7 @@ func handleAddSuppliers() {
 
 // handleDeleteSupplier enables the deletion of suppliers from the list with input validation and cancellation
 func handleDeleteSupplier() {
-	filePath := "supplier.csv"
+	filePath := settings.SupplierPath()
 
 	for {
 		// Read the list of suppliers from the CSV file
@@ -524,21 +759,94 @@ func handleDeleteSupplier() {
 
 		// Perform deletion
 		supplierToDelete := suppliers[index-1]
-		err = Supplier.DeleteSupplier(filePath, index-1)
+		deleted, err := deleteListEntry(Audit.EntitySupplier, supplierToDelete)
 		if err != nil {
 			console.ErrorMessage(fmt.Sprintf("❌ Error deleting supplier: %v", err))
 			return
 		}
+		if !deleted {
+			console.ShowMessage(fmt.Sprintf("Supplier '%s' was kept.", supplierToDelete))
+			continue
+		}
 
 		// Confirm successful deletion
 		console.ShowMessage(fmt.Sprintf("✅ Supplier '%s' deleted successfully.", supplierToDelete))
 	}
 }
 
-// handleShowCategories displays a list of categories and allows navigation or exiting
+// handleEditSupplierDetails lets the user change the contact and account data of a supplier
+func handleEditSupplierDetails() {
+	filePath := settings.SupplierPath()
+
+	for {
+		suppliers, err := Supplier.ReadSupplierRecords(filePath)
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error reading suppliers: %v", err))
+			return
+		}
+		if len(suppliers) == 0 {
+			console.ShowNoSuppliersMessage()
+			return
+		}
+		console.ShowSuppliersList(Supplier.Names(suppliers))
+
+		console.ShowMessage("Enter the number of the supplier you want to edit (or 'C' to cancel):")
+		input := console.GetUserInput()
+		if input == "C" || input == "c" {
+			console.ShowMessage("Action canceled. Returning to the service menu...")
+			return
+		}
+		index, err := strconv.Atoi(input)
+		if err != nil || index < 1 || index > len(suppliers) {
+			console.ErrorMessage("Invalid input. Please enter a valid supplier number.")
+			continue
+		}
+
+		supplier := suppliers[index-1]
+		console.Clear()
+		console.ShowSupplierDetails(supplier)
+		anything := func(string) bool { return true }
+		supplier.ContactPerson = console.AskForSupplierDetail("Contact person", supplier.ContactPerson, anything)
+		supplier.Email = console.AskForSupplierDetail("Email", supplier.Email, Supplier.IsValidEmail)
+		supplier.Phone = console.AskForSupplierDetail("Phone", supplier.Phone, Supplier.IsValidPhone)
+		supplier.Address = console.AskForSupplierDetail("Address", supplier.Address, anything)
+		supplier.Website = console.AskForSupplierDetail("Website", supplier.Website, anything)
+		supplier.CustomerNumber = console.AskForSupplierDetail("Customer number", supplier.CustomerNumber, anything)
+		supplier.PaymentTerms = console.AskForSupplierDetail("Payment terms", supplier.PaymentTerms, anything)
+
+		console.Clear()
+		console.ShowSupplierDetails(supplier)
+		console.ShowMessage("Save these details? (y/n)")
+		if strings.ToLower(console.AskForInput()) != "y" {
+			console.HandleChancelAction()
+			continue
+		}
+		err = saveUndoableListChange(fmt.Sprintf("edit supplier %s", supplier.SupplierName), Audit.EntitySupplier, func() error {
+			return Supplier.UpdateSupplier(filePath, supplier)
+		})
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error saving supplier: %v", err))
+			continue
+		}
+		console.ShowMessage("✅ Supplier details saved.")
+	}
+}
+
+// showSupplierDetails shows the contact and account data of the chosen supplier, if it has any
+func showSupplierDetails(supplierName string) {
+	suppliers, err := Supplier.ReadSupplierRecords(settings.SupplierPath())
+	if err != nil {
+		return
+	}
+	if supplier, ok := Supplier.FindSupplier(suppliers, supplierName); ok {
+		console.ShowSupplierDetails(supplier)
+	}
+}
+
+// handleShowCategories displays the category tree with the number of articles and allows navigation or exiting
 func handleShowCategories() {
 	// Calculate the start and end indices for the current page
-	categories, err := Category.ReadCategories("categories.csv")
+	categories, err := readCategoryTree()
 	if err != nil {
 		console.ErrorMessage(fmt.Sprintf("❌ Error reading categories: %v\n", err))
 		return
@@ -549,16 +857,19 @@ func handleShowCategories() {
 		return
 	}
 
+	// Every category counts the articles of its subcategories as well
+	activeItems := models.GetActiveItems(store.GetAllItems())
+	articleCounts := map[string]int{}
+	for _, node := range categories {
+		articleCounts[node.CategoryName] = len(itemsInCategories(activeItems, Category.Descendants(categories, node.CategoryName)))
+	}
+
 	page := InitialPage
 	for {
 		// Calculate the start and end indices for the current page
-		start := page * PageSize
-		end := start + PageSize
-		if end > len(categories) {
-			end = len(categories)
-		}
+		start, end := console.PageIndexCalculate(page, console.PageSize, len(categories))
 
-		console.DisplayCategories(categories, start, end)
+		console.DisplayCategories(categories, articleCounts, start, end)
 
 		// Check if the end of the list has been reached
 		if end >= len(categories) {
@@ -586,11 +897,11 @@ func handleShowCategories() {
 
 // handleAddCategories allows the user to add new categories to the list with an option to cancel
 func handleAddCategories() {
-	filePath := "categories.csv"
+	filePath := settings.CategoriesPath()
 
 	for {
 		// Display the list of existing categories
-		categories, err := Category.ReadCategories(filePath)
+		categories, err := readCategoryTree()
 		if err != nil {
 			console.ErrorMessage(fmt.Sprintf("❌ Error reading categories: %v", err))
 			return
@@ -607,13 +918,31 @@ func handleAddCategories() {
 		}
 
 		// Validate the category name
-		if !Category.IsValidCategoryName(categoryName) {
-			console.ShowMessage("❌ Invalid category name. Please use only letters, numbers, and spaces, and ensure it is not empty.")
+		categoryName, err = settings.NameRules().Check(categoryName, Category.NodeNames(categories))
+		if err != nil {
+			console.ShowMessage(fmt.Sprintf("❌ Invalid category name: %v.", err))
 			continue
 		}
 
+		// A category can be added below any existing one
+		parent := ""
+		if len(categories) > 0 {
+			console.ShowMessage("Enter the number of the parent category, or press [Enter] to add it on the top level:")
+			input := console.GetUserInput()
+			if input != "" {
+				index, err := strconv.Atoi(input)
+				if err != nil || index < 1 || index > len(categories) {
+					console.ErrorMessage("Invalid input. Please enter a valid category number.")
+					continue
+				}
+				parent = categories[index-1].CategoryName
+			}
+		}
+
 		// Add the new category to the file
-		err = Category.AddCategoryToFile(filePath, categoryName)
+		err = saveUndoableListChange(fmt.Sprintf("add category %s", categoryName), Audit.EntityCategory, func() error {
+			return Category.AddSubcategory(filePath, categoryName, parent)
+		})
 		if err != nil {
 			console.ErrorMessage(fmt.Sprintf("❌ Error adding category: %v", err))
 		} else {
@@ -624,11 +953,9 @@ func handleAddCategories() {
 
 // handleDeleteCategories enables the deletion of categories from the list with input validation and cancellation
 func handleDeleteCategories() {
-	filePath := "categories.csv"
-
 	for {
 		// Read the list of categories from the CSV file
-		categories, err := Category.ReadCategories(filePath)
+		categories, err := readCategoryTree()
 		if err != nil {
 			console.ErrorMessage(fmt.Sprintf("❌ Error reading categories: %v", err))
 			return
@@ -661,12 +988,16 @@ func handleDeleteCategories() {
 		}
 
 		// Perform deletion
-		categoryToDelete := categories[index-1]
-		err = Category.DeleteCategory(filePath, index-1)
+		categoryToDelete := categories[index-1].CategoryName
+		deleted, err := deleteListEntry(Audit.EntityCategory, categoryToDelete)
 		if err != nil {
 			console.ErrorMessage(fmt.Sprintf("❌ Error deleting category: %v", err))
 			return
 		}
+		if !deleted {
+			console.ShowMessage(fmt.Sprintf("Category '%s' was kept.", categoryToDelete))
+			continue
+		}
 
 		// Confirm successful deletion
 		console.ShowMessage(fmt.Sprintf("✅ Category '%s' deleted successfully.", categoryToDelete))
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"fmt"
+	"it_inventar/models"
+	"it_inventar/models/Audit"
+	"it_inventar/views/console"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// *handleViewDeletedItems: Shows all deleted items and lets the user restore or purge them
+// *handleViewDeletedItems: Zeigt alle gelöschten Gegenstände und erlaubt, sie wiederherzustellen oder endgültig zu entfernen
+func handleViewDeletedItems() {
+	page := InitialPage
+	for {
+		deletedItems := models.GetDeletedItems(store.GetAllItems())
+		console.Clear()
+		if len(deletedItems) == 0 {
+			console.ShowMessage("⚠️ There are no deleted items.")
+			console.ShowContinue()
+			console.Clear()
+			return
+		}
+		if page*console.PageSize >= len(deletedItems) {
+			page = InitialPage
+		}
+
+		start, end := console.PageIndexCalculate(page, console.PageSize, len(deletedItems))
+		console.ShowAllItems(deletedItems[start:end], true) // showDeletedDate = true
+		console.ShowMessage("Enter the ID(s) of the items to restore or purge (e.g. 3 or 3,7), press [Enter] for next page or [c] to return to the service menu.")
+
+		choice := console.AskForInput()
+		switch strings.ToLower(choice) {
+		case "c":
+			console.Clear()
+			return
+		case "":
+			page++
+			continue
+		}
+
+		selected, err := parseItemIds(choice, deletedItems)
+		if err != nil {
+			console.ShowMessage(fmt.Sprintf("❌ %v", err))
+			console.ShowContinue()
+			continue
+		}
+		handleDeletedItemsAction(selected)
+	}
+}
+
+// handleDeletedItemsAction asks whether the selected deleted items are restored or purged and does it
+func handleDeletedItemsAction(selected []models.Item) {
+	console.Clear()
+	var ids []int
+	for _, item := range selected {
+		console.ShowMessage(console.ConfirmTheArticle(item))
+		ids = append(ids, item.ID)
+	}
+	console.ShowDeletedItemActions()
+
+	switch strings.ToLower(console.AskForInput()) {
+	case "r":
+		restored := 0
+		for _, item := range selected {
+			replacements, ok := askForValidReferences(item)
+			if !ok {
+				console.ShowMessage(fmt.Sprintf("Item %d was not restored.", item.ID))
+				continue
+			}
+			restore := func() error {
+				return store.RestoreItem(item.ID, func(restored *models.Item) {
+					for kind, name := range replacements {
+						restored.SetReference(kind, name)
+					}
+				})
+			}
+			if err := saveUndoableItemChange(fmt.Sprintf("restore item %d", item.ID), restore); err != nil {
+				console.ShowError(err)
+				console.ShowContinue()
+				return
+			}
+			restored++
+		}
+		console.ShowMessage(fmt.Sprintf("✅ %d item(s) restored!", restored))
+	case "p":
+		console.ShowMessage("⚠️ Purged items are removed from the inventory for good and only kept in the archive file. Continue? (y/n)")
+		if strings.ToLower(console.AskForInput()) != "y" {
+			console.HandleChancelAction()
+			return
+		}
+		if err := saveChange(func() error { return store.PurgeItems(ids) }); err != nil {
+			console.ShowError(err)
+			console.ShowContinue()
+			return
+		}
+		console.ShowMessage(fmt.Sprintf("✅ %d item(s) purged and archived!", len(ids)))
+	default:
+		console.HandleChancelAction()
+		return
+	}
+	console.ShowContinue()
+}
+
+// askForValidReferences checks that the category and the supplier of the deleted item still exist, e.g. they may have
+// been deleted together with it. For each missing one the user chooses another, false means the user canceled.
+func askForValidReferences(item models.Item) (map[string]string, bool) {
+	replacements := map[string]string{}
+	for _, kind := range []string{Audit.EntityCategory, Audit.EntitySupplier} {
+		list, err := readList(kind)
+		if err != nil {
+			console.ShowError(err)
+			return nil, false
+		}
+		if slices.Contains(list, item.Reference(kind)) {
+			continue
+		}
+		if len(list) == 0 {
+			console.ShowMessage(fmt.Sprintf("⚠️ The %s '%s' of %s (ID %d) no longer exists and there is no other %s, please add one in the service menu first.", kind, item.Reference(kind), item.ArticleName, item.ID, kind))
+			return nil, false
+		}
+		console.ShowMessage(fmt.Sprintf("⚠️ The %s '%s' of %s (ID %d) no longer exists, please choose another one:", kind, item.Reference(kind), item.ArticleName, item.ID))
+		var replacement string
+		if kind == Audit.EntityCategory {
+			categoryTree, err := readCategoryTree()
+			if err != nil {
+				console.ShowError(err)
+				return nil, false
+			}
+			replacement = console.HandleAddSelectCategory("", categoryTree, false)
+		} else {
+			replacement = console.SelectItem(list, console.PageSize, capitalize(kind))
+		}
+		if replacement == "C" || replacement == "" {
+			return nil, false
+		}
+		replacements[kind] = replacement
+	}
+	return replacements, true
+}
+
+// parseItemIds reads a comma separated list of IDs, every ID has to belong to one of the passed items
+func parseItemIds(input string, items []models.Item) ([]models.Item, error) {
+	var selected []models.Item
+	for _, part := range strings.Split(input, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q", strings.TrimSpace(part))
+		}
+		found := false
+		for _, item := range items {
+			if item.ID == id {
+				selected = append(selected, item)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no deleted item with ID %d", id)
+		}
+	}
+	return selected, nil
+}
+
+// applyRetentionPolicy moves items deleted longer ago than the configured number of days to the archive
+func applyRetentionPolicy() {
+	if settings.PurgeAfterDays <= 0 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -settings.PurgeAfterDays)
+	count, err := store.PurgeDeletedBefore(cutoff)
+	if err != nil {
+		console.ShowError(fmt.Errorf("purging old deleted items: %v", err))
+		return
+	}
+	if count > 0 {
+		console.ShowMessage(fmt.Sprintf("🗄️ %d item(s) deleted more than %d days ago were moved to the archive.", count, settings.PurgeAfterDays))
+		console.ShowContinue()
+	}
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"it_inventar/config"
+	"it_inventar/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+// useTestInventory runs the controllers on an in-memory store with the passed items, the other files are kept in a
+// new data directory
+func useTestInventory(t *testing.T, items []models.Item) {
+	t.Helper()
+	settings = config.Default()
+	settings.DataDir = t.TempDir()
+	store = models.NewMemoryItemStore(items)
+}
+
+// deletedItem returns an item that was deleted the passed number of days ago
+func deletedItem(id int, name string, days int) models.Item {
+	deleted := time.Now().AddDate(0, 0, -days)
+	return models.Item{ID: id, ArticleName: name, ArticleNumber: name, Quantity: 1, IsDeleted: true, DeleteDate: &deleted}
+}
+
+func TestApplyRetentionPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		purgeAfterDays int
+		want           []int
+	}{
+		{name: "disabled", purgeAfterDays: 0, want: []int{1, 2, 3}},
+		{name: "after 30 days", purgeAfterDays: 30, want: []int{1, 3}},
+		{name: "after 3 days", purgeAfterDays: 3, want: []int{1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useTestInventory(t, []models.Item{
+				{ID: 1, ArticleName: "Monitor", ArticleNumber: "M001", Quantity: 3},
+				deletedItem(2, "Maus", 40),
+				deletedItem(3, "Tastatur", 5),
+			})
+			settings.PurgeAfterDays = test.purgeAfterDays
+
+			applyRetentionPolicy()
+			if got := itemIds(store.GetAllItems()); !slices.Equal(got, test.want) {
+				t.Errorf("items %v are left, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"fmt"
+	"it_inventar/models"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Employee"
+	"it_inventar/models/Ledger"
+	"it_inventar/models/Supplier"
+	"it_inventar/views/console"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// handleEmployees shows the employee register and adds or deletes employees until the user goes back
+func handleEmployees() {
+	filePath := settings.EmployeesPath()
+	for {
+		employees, err := Employee.ReadEmployees(filePath)
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error reading employees: %v", err))
+			return
+		}
+		console.ShowEmployees(employees)
+
+		switch strings.ToLower(console.AskForEmployeeAction()) {
+		case "a":
+			employee, ok := askForEmployee(employees)
+			if !ok {
+				continue
+			}
+			err := saveUndoableListChange(fmt.Sprintf("add employee %s", employee.EmployeeName), Audit.EntityEmployee, func() error {
+				return Employee.AddEmployee(filePath, employee)
+			})
+			if err != nil {
+				console.ErrorMessage(fmt.Sprintf("❌ Error adding employee: %v", err))
+				continue
+			}
+			console.ShowMessage(fmt.Sprintf("✅ Employee '%s' added.", employee.EmployeeName))
+		case "d":
+			if len(employees) == 0 {
+				console.ShowMessage("⚠️ No employees registered yet.")
+				continue
+			}
+			console.ShowMessage("Enter the number of the employee you want to delete:")
+			index, err := strconv.Atoi(console.GetUserInput())
+			if err != nil || index < 1 || index > len(employees) {
+				console.ErrorMessage("Invalid input. Please enter a valid employee number.")
+				continue
+			}
+			name := employees[index-1].EmployeeName
+			// The equipment would be lost from sight, it has to come back first
+			if holdings := models.HoldingsOf(store.GetAllItems(), name); len(holdings) > 0 {
+				console.ShowMessage(fmt.Sprintf("❌ %s still has %d article(s), please check them in first:", name, len(holdings)))
+				console.ShowEmployeeHoldings(name, holdings)
+				continue
+			}
+			err = saveUndoableListChange(fmt.Sprintf("delete employee %s", name), Audit.EntityEmployee, func() error {
+				return Employee.DeleteEmployee(filePath, name)
+			})
+			if err != nil {
+				console.ErrorMessage(fmt.Sprintf("❌ Error deleting employee: %v", err))
+				continue
+			}
+			console.ShowMessage(fmt.Sprintf("✅ Employee '%s' deleted.", name))
+		case "c":
+			return
+		default:
+			console.ShowMessage("❌ Invalid selection. Please try again.")
+		}
+	}
+}
+
+// askForEmployee asks for the name, department and email of a new employee, the name must not be registered yet
+func askForEmployee(existing []models.Employee) (models.Employee, bool) {
+	console.ShowMessage("Enter the name of the employee:")
+	name, err := settings.NameRules().Check(console.GetUserInput(), Employee.Names(existing))
+	if err != nil {
+		console.ShowMessage(fmt.Sprintf("❌ Invalid employee name: %v.", err))
+		return models.Employee{}, false
+	}
+	employee := models.Employee{EmployeeName: name}
+	employee.Department = console.AskForEmployeeDetail("Department", "", func(string) bool { return true })
+	employee.Email = console.AskForEmployeeDetail("Email", "", Supplier.IsValidEmail)
+	return employee, true
+}
+
+// selectEmployee lets the user choose an employee of the register, false when there is none or the user cancels
+func selectEmployee() (string, bool) {
+	employees, err := Employee.ReadEmployees(settings.EmployeesPath())
+	if err != nil {
+		console.ErrorMessage(fmt.Sprintf("❌ Error reading employees: %v", err))
+		return "", false
+	}
+	if len(employees) == 0 {
+		console.ShowMessage("⚠️ No employees registered yet. Employees are added in the service menu.")
+		return "", false
+	}
+	name := console.HandleAddSelectItem("", Employee.Names(employees), "employee", false)
+	return name, name != "C"
+}
+
+// handleCheckOut gives pieces in stock to an employee, pieces with serial numbers are chosen one by one
+func handleCheckOut() {
+	console.Clear()
+	employee, ok := selectEmployee()
+	if !ok {
+		finishAssignment()
+		return
+	}
+
+	inStock := slices.DeleteFunc(models.GetActiveItems(store.GetAllItems()), func(item models.Item) bool { return item.Quantity == 0 })
+	if len(inStock) == 0 {
+		console.ShowMessage("❌ No article is in stock.")
+		finishAssignment()
+		return
+	}
+	item, ok := selectItem(inStock)
+	if !ok {
+		return
+	}
+
+	var serialNumbers []string
+	count := 0
+	if item.TracksAssets() {
+		console.ShowAssets(item.AssetsWithStatus(models.AssetInStock))
+		console.ShowMessage(fmt.Sprintf("Enter the numbers or serial numbers of the pieces %s gets (e.g. 1,3 or SN123):", employee))
+		chosen, err := chooseAssets(console.AskForInput(), item.AssetsWithStatus(models.AssetInStock))
+		if err != nil {
+			console.ShowMessage(fmt.Sprintf("❌ %v", err))
+			finishAssignment()
+			return
+		}
+		serialNumbers, count = chosen, len(chosen)
+	} else {
+		console.ShowMessage(fmt.Sprintf("Current stock: %d pieces", item.Quantity))
+		console.ShowMessage(fmt.Sprintf("Enter the quantity %s gets:", employee))
+		count = askForPieces(-1)
+	}
+	location, ok := askForStockLocation(item, true)
+	if !ok {
+		finishAssignment()
+		return
+	}
+
+	booking := Ledger.Movement{Reason: Ledger.ReasonIssue, Location: location, Comment: "check out to " + employee}
+	saveAssignment(item.ID, fmt.Sprintf("check out %d pieces of %s to %s", count, item.ArticleName, employee), booking, func(current *models.Item) error {
+		// Checking out takes the pieces without location first, so the ones from the chosen location are moved there
+		if location != models.NoLocation {
+			if err := current.Transfer(location, models.NoLocation, count); err != nil {
+				return err
+			}
+		}
+		return current.CheckOut(employee, serialNumbers, count)
+	})
+}
+
+// askForPieces asks for the pieces to book until at least one is entered, before the stock is checked.
+// A defaultValue of -1 has no default, else "Enter" takes it.
+func askForPieces(defaultValue int) int {
+	for {
+		count := console.AskForQuantity(defaultValue, defaultValue >= 0)
+		if count >= 1 {
+			return count
+		}
+		console.ShowMessage("⚠️ Please enter at least 1 piece.")
+	}
+}
+
+// handleCheckIn takes pieces an employee has back into stock
+func handleCheckIn() {
+	console.Clear()
+	employee, ok := selectEmployee()
+	if !ok {
+		finishAssignment()
+		return
+	}
+
+	holdings := models.HoldingsOf(store.GetAllItems(), employee)
+	if len(holdings) == 0 {
+		console.ShowMessage(fmt.Sprintf("⚠️ %s has no articles.", employee))
+		finishAssignment()
+		return
+	}
+	console.ShowEmployeeHoldings(employee, holdings)
+	console.ShowMessage("Enter the number of the article to check in (or 'C' to cancel):")
+	input := console.AskForInput()
+	index, err := strconv.Atoi(input)
+	if strings.ToLower(input) == "c" || err != nil || index < 1 || index > len(holdings) {
+		if strings.ToLower(input) != "c" {
+			console.ErrorMessage("Invalid input. Please enter a valid article number.")
+		}
+		finishAssignment()
+		return
+	}
+	holding := holdings[index-1]
+
+	// Pieces without serial number are counted, the others are chosen from the ones the employee has
+	serialNumbers := holding.SerialNumbers
+	count := holding.Count - len(holding.SerialNumbers)
+	if len(holding.SerialNumbers) > 0 {
+		var assets []models.Asset
+		for _, asset := range holding.Item.AssetsWithStatus(models.AssetCheckedOut) {
+			if asset.Employee == employee {
+				assets = append(assets, asset)
+			}
+		}
+		console.ShowAssets(assets)
+		console.ShowMessage("Enter the numbers or serial numbers of the pieces to check in, or press [Enter] for all:")
+		if input := console.AskForInput(); input != "" {
+			serialNumbers, err = chooseAssets(input, assets)
+			if err != nil {
+				console.ShowMessage(fmt.Sprintf("❌ %v", err))
+				finishAssignment()
+				return
+			}
+		}
+	} else {
+		console.ShowMessage(fmt.Sprintf("%s has %d pieces. Enter the quantity to check in [Enter for all]:", employee, count))
+		count = askForPieces(count)
+	}
+
+	pieces := count
+	if len(serialNumbers) > 0 {
+		pieces = len(serialNumbers)
+	}
+	location, ok := askForStockLocation(holding.Item, false)
+	if !ok {
+		finishAssignment()
+		return
+	}
+	booking := Ledger.Movement{Reason: Ledger.ReasonReturn, Location: location, Comment: "check in from " + employee}
+	saveAssignment(holding.Item.ID, fmt.Sprintf("check in %d pieces of %s from %s", pieces, holding.Item.ArticleName, employee), booking, func(current *models.Item) error {
+		if err := current.CheckIn(employee, serialNumbers, count); err != nil {
+			return err
+		}
+		if location == models.NoLocation {
+			return nil
+		}
+		return current.Transfer(models.NoLocation, location, pieces)
+	})
+}
+
+// handleShowAssignments shows what an employee has or who has an article
+func handleShowAssignments() {
+	console.Clear()
+	console.ShowMessage("[e] What does an employee have?\n[a] Who has an article?")
+	switch strings.ToLower(console.AskForInput()) {
+	case "e":
+		employee, ok := selectEmployee()
+		if !ok {
+			break
+		}
+		if holdings := models.HoldingsOf(store.GetAllItems(), employee); len(holdings) > 0 {
+			console.ShowEmployeeHoldings(employee, holdings)
+		} else {
+			console.ShowMessage(fmt.Sprintf("%s has no articles.", employee))
+		}
+	case "a":
+		handedOut := slices.DeleteFunc(models.GetActiveItems(store.GetAllItems()), func(item models.Item) bool { return len(item.Holdings()) == 0 })
+		if len(handedOut) == 0 {
+			console.ShowMessage("No article is checked out.")
+			break
+		}
+		item, ok := selectItem(handedOut)
+		if !ok {
+			return
+		}
+		console.ShowItemHolders(item, item.Holdings())
+	default:
+		console.ShowMessage("❌ Invalid selection. Please try again.")
+	}
+	finishAssignment()
+}
+
+// selectItem shows the items page by page until the user enters the ID of one, false when the user returns to the menu
+func selectItem(items []models.Item) (models.Item, bool) {
+	page := InitialPage
+	for {
+		start, end := console.PageIndexCalculate(page, console.PageSize, len(items))
+		console.ShowAllItems(items[start:end], false)
+
+		exit, item, _ := console.PageIndexUserInput(console.PageIndexPrompt("Item"), &page, end, items)
+		if exit {
+			return models.Item{}, false
+		}
+		if item != nil {
+			return *item, true
+		}
+	}
+}
+
+// saveAssignment applies the check-out or check-in to the current state of the item and saves it with the booking
+// in the ledger
+func saveAssignment(id int, description string, booking Ledger.Movement, change func(current *models.Item) error) {
+	var saved models.Item
+	before := 0
+	err := saveBooking(description, booking, func() error {
+		current, err := store.GetItemByID(id)
+		if err != nil {
+			return err
+		}
+		before = current.Quantity
+		if err := change(&current); err != nil {
+			return err
+		}
+		saved = current
+		return store.UpdateItem(id, current)
+	})
+	if err != nil {
+		console.ShowMessage(fmt.Sprintf("❌ %v", err))
+	} else {
+		console.ShowMessage(fmt.Sprintf("New stock: %d pieces", saved.Quantity))
+		console.ShowMessage(fmt.Sprintf("✅ Done: %s.", description))
+		warnBelowMinimum(saved, before)
+	}
+	finishAssignment()
+}
+
+// finishAssignment waits for the user and returns to the main menu
+func finishAssignment() {
+	console.ShowContinue()
+	console.Clear()
+	console.ShowExecuteCommandMenu()
+}
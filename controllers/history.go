@@ -0,0 +1,505 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"it_inventar/models"
+	"it_inventar/models/Attribute"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Category"
+	"it_inventar/models/Employee"
+	"it_inventar/models/Ledger"
+	"it_inventar/models/Location"
+	"it_inventar/models/Storage"
+	"it_inventar/models/Supplier"
+	"it_inventar/views/console"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// command is a change that can be undone and redone. It keeps the complete states before and after the change,
+// so undoing it doesn't depend on the handler that made it.
+type command struct {
+	Description string       `json:"description"`
+	Time        time.Time    `json:"time"`
+	Items       []itemChange `json:"items,omitempty"`
+	Lists       []listChange `json:"lists,omitempty"`
+}
+
+// itemChange is one item before and after a command, nil means the item did not exist
+type itemChange struct {
+	ID     int          `json:"id"`
+	Before *models.Item `json:"before"`
+	After  *models.Item `json:"after"`
+}
+
+// listChange is the list of categories or suppliers before and after a command, Kind is the audit entity of the list
+type listChange struct {
+	Kind   string   `json:"kind"`
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+	// BeforeSuppliers and AfterSuppliers are the complete supplier records, so undo also brings back their details
+	BeforeSuppliers []models.Supplier `json:"beforeSuppliers,omitempty"`
+	AfterSuppliers  []models.Supplier `json:"afterSuppliers,omitempty"`
+	// BeforeCategories and AfterCategories are the categories with their parents, so undo also restores the tree
+	BeforeCategories []models.Category `json:"beforeCategories,omitempty"`
+	AfterCategories  []models.Category `json:"afterCategories,omitempty"`
+	// BeforeAttributes and AfterAttributes are the attribute definitions of the categories, nil in older commands
+	BeforeAttributes *[]Attribute.Definition `json:"beforeAttributes,omitempty"`
+	AfterAttributes  *[]Attribute.Definition `json:"afterAttributes,omitempty"`
+	// BeforeEmployees and AfterEmployees are the complete employee records of the employee register
+	BeforeEmployees []models.Employee `json:"beforeEmployees,omitempty"`
+	AfterEmployees  []models.Employee `json:"afterEmployees,omitempty"`
+}
+
+// listState is the content of the category, supplier, employee or location file, only the records of its own kind
+// are set. Locations only have names.
+// The attribute definitions belong to the categories, as they are renamed together with them.
+type listState struct {
+	Names      []string
+	Suppliers  []models.Supplier
+	Categories []models.Category
+	Attributes *[]Attribute.Definition
+	Employees  []models.Employee
+}
+
+// newListChange returns the change of a list from one state to another
+func newListChange(kind string, before, after listState) listChange {
+	return listChange{
+		Kind:             kind,
+		Before:           before.Names,
+		After:            after.Names,
+		BeforeSuppliers:  before.Suppliers,
+		AfterSuppliers:   after.Suppliers,
+		BeforeCategories: before.Categories,
+		AfterCategories:  after.Categories,
+		BeforeAttributes: before.Attributes,
+		AfterAttributes:  after.Attributes,
+		BeforeEmployees:  before.Employees,
+		AfterEmployees:   after.Employees,
+	}
+}
+
+// target returns the state the list has before (undo) or after (redo) the command
+func (l listChange) target(undo bool) listState {
+	if undo {
+		return listState{Names: l.Before, Suppliers: l.BeforeSuppliers, Categories: l.BeforeCategories, Attributes: l.BeforeAttributes, Employees: l.BeforeEmployees}
+	}
+	return listState{Names: l.After, Suppliers: l.AfterSuppliers, Categories: l.AfterCategories, Attributes: l.AfterAttributes, Employees: l.AfterEmployees}
+}
+
+// history holds the commands that can be undone and redone, the newest command is last
+type history struct {
+	Undo []command `json:"undo"`
+	Redo []command `json:"redo"`
+}
+
+// loadHistory reads the history file, a missing file is an empty history
+func loadHistory() (history, error) {
+	var loaded history
+	content, err := os.ReadFile(settings.HistoryPath())
+	if os.IsNotExist(err) {
+		return loaded, nil
+	}
+	if err != nil {
+		return loaded, err
+	}
+	if err := json.Unmarshal(content, &loaded); err != nil {
+		return loaded, fmt.Errorf("the undo history %s is invalid: %v", settings.HistoryPath(), err)
+	}
+	return loaded, nil
+}
+
+// saveHistory writes the history file, only the configured number of commands is kept on each stack
+func saveHistory(changed history) error {
+	for _, stack := range []*[]command{&changed.Undo, &changed.Redo} {
+		if len(*stack) > settings.UndoLevels {
+			*stack = (*stack)[len(*stack)-settings.UndoLevels:]
+		}
+	}
+	return Storage.WriteFileAtomic(settings.HistoryPath(), func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(changed)
+	})
+}
+
+// recordCommand puts a new command on the undo stack, a new command makes the undone commands unreachable
+func recordCommand(newCommand command) {
+	if settings.UndoLevels == 0 || (len(newCommand.Items) == 0 && len(newCommand.Lists) == 0) {
+		return
+	}
+	newCommand.Time = time.Now()
+
+	current, err := loadHistory()
+	if err == nil {
+		current.Undo = append(current.Undo, newCommand)
+		current.Redo = nil
+		err = saveHistory(current)
+	}
+	if err != nil {
+		console.ErrorMessage(fmt.Sprintf("❌ The change was saved, but it can't be undone: %v", err))
+	}
+}
+
+// saveUndoableItemChange runs the change like saveChange and records every item it changed on the undo stack.
+// Changed quantities are recorded in the ledger as corrections.
+func saveUndoableItemChange(description string, change func() error) error {
+	return saveBooking(description, Ledger.Movement{Reason: Ledger.ReasonCorrection, Comment: description}, change)
+}
+
+// saveBooking runs the change like saveUndoableItemChange, the changed quantities are recorded in the ledger with
+// the reason, location, reference and comment of the booking
+func saveBooking(description string, booking Ledger.Movement, change func() error) error {
+	var before []models.Item
+	err := saveChange(func() error {
+		// Taken on every attempt, so changes of other users loaded in between don't become part of the command
+		before = store.GetAllItems()
+		return change()
+	})
+	if err != nil {
+		return err
+	}
+	after := store.GetAllItems()
+	recordCommand(command{Description: description, Items: changedItems(before, after)})
+	recordMovements(before, after, booking)
+	return nil
+}
+
+// saveUndoableListChange runs a change of the categories or suppliers and records the list on the undo stack
+func saveUndoableListChange(description, kind string, change func() error) error {
+	return saveUndoableReferenceChange(description, kind, nil, change)
+}
+
+// saveUndoableReferenceChange first changes the items that refer to a category or supplier, then the list itself.
+// Both are recorded as one command, so they are undone together. Either change may be nil, e.g. when the
+// item change already writes the list in the same transaction.
+func saveUndoableReferenceChange(description, kind string, itemChange, change func() error) error {
+	listBefore, err := readListState(kind)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	newCommand := command{Description: description}
+	if itemChange != nil {
+		var itemsBefore []models.Item
+		err := saveChange(func() error {
+			itemsBefore = store.GetAllItems()
+			return itemChange()
+		})
+		if err != nil {
+			return err
+		}
+		newCommand.Items = changedItems(itemsBefore, store.GetAllItems())
+	}
+
+	// When the list can't be changed, the item change is still recorded, so it can be undone
+	var listErr error
+	if change != nil {
+		listErr = change()
+	}
+	if listErr == nil {
+		listAfter, err := readListState(kind)
+		if err != nil {
+			listErr = err
+		} else {
+			newCommand.Lists = []listChange{newListChange(kind, listBefore, listAfter)}
+		}
+	}
+	recordCommand(newCommand)
+	return listErr
+}
+
+// changedItems compares the items before and after a change and returns the ones that differ
+func changedItems(before, after []models.Item) []itemChange {
+	states := map[int]*itemChange{}
+	var order []int
+	for _, item := range before {
+		states[item.ID] = &itemChange{ID: item.ID, Before: &item}
+		order = append(order, item.ID)
+	}
+	for _, item := range after {
+		if state, ok := states[item.ID]; ok {
+			state.After = &item
+			continue
+		}
+		states[item.ID] = &itemChange{ID: item.ID, After: &item}
+		order = append(order, item.ID)
+	}
+
+	var changes []itemChange
+	for _, id := range order {
+		state := states[id]
+		if state.Before != nil && state.After != nil && state.Before.Equal(*state.After) {
+			continue
+		}
+		changes = append(changes, *state)
+	}
+	return changes
+}
+
+// readList reads the names of the categories in tree order or the names of the suppliers
+func readList(kind string) ([]string, error) {
+	if kind == Audit.EntityCategory {
+		categories, err := readCategoryTree()
+		return Category.NodeNames(categories), err
+	}
+	return Supplier.ReadSuppliers(settings.SupplierPath())
+}
+
+// readListState reads the names and the complete records of the categories, suppliers, employees or locations
+func readListState(kind string) (listState, error) {
+	if kind == Audit.EntityLocation {
+		locations, err := Location.ReadLocations(settings.LocationsPath())
+		return listState{Names: locations}, err
+	}
+	if kind == Audit.EntityEmployee {
+		employees, err := Employee.ReadEmployees(settings.EmployeesPath())
+		return listState{Names: Employee.Names(employees), Employees: employees}, err
+	}
+	if kind == Audit.EntityCategory {
+		categories, err := Category.ReadCategoryRecords(settings.CategoriesPath())
+		if err != nil {
+			return listState{}, err
+		}
+		attributes, err := Attribute.ReadDefinitions(settings.AttributesPath())
+		return listState{Names: Category.Names(categories), Categories: categories, Attributes: &attributes}, err
+	}
+	suppliers, err := Supplier.ReadSupplierRecords(settings.SupplierPath())
+	return listState{Names: Supplier.Names(suppliers), Suppliers: suppliers}, err
+}
+
+// writeList replaces the categories, the suppliers, the employees or the locations. Commands recorded before the
+// lists had more than names only know the names, their entries are written without details on the top level.
+func writeList(kind string, state listState) error {
+	if kind == Audit.EntityLocation {
+		return Location.SetLocations(settings.LocationsPath(), state.Names)
+	}
+	if kind == Audit.EntityEmployee {
+		return Employee.SetEmployees(settings.EmployeesPath(), state.Employees)
+	}
+	if kind == Audit.EntityCategory {
+		if err := Category.SetCategories(settings.CategoriesPath(), state.categories()); err != nil {
+			return err
+		}
+		if state.Attributes == nil {
+			return nil
+		}
+		return Attribute.SetDefinitions(settings.AttributesPath(), *state.Attributes)
+	}
+	return Supplier.SetSuppliers(settings.SupplierPath(), state.suppliers())
+}
+
+// listWrites returns the writes that put the list into the passed state, so it can be saved in the same transaction
+// as the items, and the audit entries of each file. The caller holds the locks of the listPaths.
+func listWrites(kind string, state listState) ([]Storage.FileWrite, [][]Audit.Entry, error) {
+	var write Storage.FileWrite
+	var entries []Audit.Entry
+	var err error
+	switch kind {
+	case Audit.EntityLocation:
+		write, entries, err = Location.SetLocationsWrite(settings.LocationsPath(), state.Names)
+	case Audit.EntityEmployee:
+		write, entries, err = Employee.SetEmployeesWrite(settings.EmployeesPath(), state.Employees)
+	case Audit.EntityCategory:
+		write, entries, err = Category.SetCategoriesWrite(settings.CategoriesPath(), state.categories())
+		if err != nil || state.Attributes == nil {
+			return []Storage.FileWrite{write}, [][]Audit.Entry{entries}, err
+		}
+		attributesWrite, attributeEntries, err := Attribute.SetDefinitionsWrite(settings.AttributesPath(), *state.Attributes)
+		return []Storage.FileWrite{write, attributesWrite}, [][]Audit.Entry{entries, attributeEntries}, err
+	default:
+		write, entries, err = Supplier.SetSuppliersWrite(settings.SupplierPath(), state.suppliers())
+	}
+	return []Storage.FileWrite{write}, [][]Audit.Entry{entries}, err
+}
+
+// listPaths returns the files of the list, the attribute definitions are part of the categories
+func listPaths(kind string) []string {
+	switch kind {
+	case Audit.EntityLocation:
+		return []string{settings.LocationsPath()}
+	case Audit.EntityEmployee:
+		return []string{settings.EmployeesPath()}
+	case Audit.EntityCategory:
+		return []string{settings.CategoriesPath(), settings.AttributesPath()}
+	default:
+		return []string{settings.SupplierPath()}
+	}
+}
+
+// categories returns the category records of the state, commands that only know the names put them on the top level
+func (state listState) categories() []models.Category {
+	categories := state.Categories
+	if categories == nil {
+		for _, name := range state.Names {
+			categories = append(categories, models.Category{CategoryName: name})
+		}
+	}
+	return categories
+}
+
+// suppliers returns the supplier records of the state, commands that only know the names give them no details
+func (state listState) suppliers() []models.Supplier {
+	suppliers := state.Suppliers
+	if suppliers == nil {
+		for _, name := range state.Names {
+			suppliers = append(suppliers, models.Supplier{SupplierName: name})
+		}
+	}
+	return suppliers
+}
+
+// handleUndo reverts the newest command and moves it to the redo stack
+func handleUndo() {
+	handleHistoryStep(true)
+	console.ShowExecuteCommandMenu()
+}
+
+// handleRedo applies the newest undone command again and moves it back to the undo stack
+func handleRedo() {
+	handleHistoryStep(false)
+	console.ShowExecuteCommandMenu()
+}
+
+// handleHistoryStep undoes or redoes the newest command of the respective stack after asking the user
+func handleHistoryStep(undo bool) {
+	console.Clear()
+	current, err := loadHistory()
+	if err != nil {
+		console.ShowError(err)
+		return
+	}
+	from, to, action := &current.Redo, &current.Undo, "Redo"
+	if undo {
+		from, to, action = &current.Undo, &current.Redo, "Undo"
+	}
+	if len(*from) == 0 {
+		console.ShowMessage(fmt.Sprintf("⚠️ Nothing to %s.", strings.ToLower(action)))
+		return
+	}
+	step := (*from)[len(*from)-1]
+
+	console.ShowMessage(fmt.Sprintf("%s \"%s\" from %s? (y/n)", action, step.Description, step.Time.Format("02.01.2006 / 15:04:05")))
+	if strings.ToLower(console.AskForInput()) != "y" {
+		console.HandleChancelAction()
+		return
+	}
+	if conflicts := changedSince(step, undo); len(conflicts) > 0 {
+		console.ShowMessage(fmt.Sprintf("⚠️ Changed since then: %s.", strings.Join(conflicts, ", ")))
+		console.ShowMessage(fmt.Sprintf("%s anyway and overwrite these changes? (y/n)", action))
+		if strings.ToLower(console.AskForInput()) != "y" {
+			console.HandleChancelAction()
+			return
+		}
+	}
+
+	if err := applyCommand(step, undo); err != nil {
+		console.ShowError(err)
+		return
+	}
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, step)
+	if err := saveHistory(current); err != nil {
+		console.ShowError(err)
+		return
+	}
+	console.ShowMessage(fmt.Sprintf("✅ %s of \"%s\" done.", action, step.Description))
+}
+
+// changedSince returns the records whose current state is not the one the command left them in
+func changedSince(step command, undo bool) []string {
+	var conflicts []string
+	for _, change := range step.Items {
+		expected := change.Before
+		if undo {
+			expected = change.After
+		}
+		current, err := store.GetItemByID(change.ID)
+		exists := err == nil
+		if exists != (expected != nil) || (exists && !current.Equal(*expected)) {
+			conflicts = append(conflicts, fmt.Sprintf("item %d", change.ID))
+		}
+	}
+	for _, list := range step.Lists {
+		// Undo expects the list as the command left it, redo as it was before the command
+		expected := list.target(!undo)
+		current, _ := readListState(list.Kind)
+		if !slices.Equal(current.Names, expected.Names) ||
+			(expected.Suppliers != nil && !slices.Equal(current.Suppliers, expected.Suppliers)) ||
+			(expected.Categories != nil && !slices.Equal(current.Categories, expected.Categories)) ||
+			(expected.Employees != nil && !slices.Equal(current.Employees, expected.Employees)) ||
+			(expected.Attributes != nil && current.Attributes != nil && !slices.EqualFunc(*current.Attributes, *expected.Attributes, Attribute.Definition.Equal)) {
+			conflicts = append(conflicts, fmt.Sprintf("%s list", list.Kind))
+		}
+	}
+	return conflicts
+}
+
+// applyCommand puts the items and lists of the command into their state before (undo) or after (redo) the command
+func applyCommand(step command, undo bool) error {
+	var states []models.ItemState
+	for _, change := range step.Items {
+		target := change.After
+		if undo {
+			target = change.Before
+		}
+		states = append(states, models.ItemState{ID: change.ID, Item: target})
+	}
+	if len(states) == 0 {
+		for _, list := range step.Lists {
+			if err := writeList(list.Kind, list.target(undo)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	before := store.GetAllItems()
+	if err := saveChange(func() error { return setItemAndListStates(states, step.Lists, undo) }); err != nil {
+		return err
+	}
+	action := "redo"
+	if undo {
+		action = "undo"
+	}
+	recordMovements(before, store.GetAllItems(), Ledger.Movement{Reason: Ledger.ReasonCorrection, Comment: fmt.Sprintf("%s of %s", action, step.Description)})
+	return nil
+}
+
+// setItemAndListStates saves the item states and the lists of the command in one transaction, so the items never
+// refer to names that are missing in the lists, e.g. when the undo of a rename fails halfway
+func setItemAndListStates(states []models.ItemState, lists []listChange, undo bool) error {
+	for _, list := range lists {
+		for _, path := range listPaths(list.Kind) {
+			lock, err := Storage.Lock(path)
+			if err != nil {
+				return err
+			}
+			defer lock.Unlock()
+		}
+	}
+
+	var files []Storage.FileWrite
+	var entries [][]Audit.Entry
+	for _, list := range lists {
+		listFiles, listEntries, err := listWrites(list.Kind, list.target(undo))
+		if err != nil {
+			return err
+		}
+		files = append(files, listFiles...)
+		entries = append(entries, listEntries...)
+	}
+	if err := store.SetItemStates(states, files...); err != nil {
+		return err
+	}
+	for index, file := range files {
+		if err := Audit.Record(file.Path, entries[index]); err != nil {
+			return fmt.Errorf("the change was saved, but could not be written to the audit log: %v", err)
+		}
+	}
+	return nil
+}
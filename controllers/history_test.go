@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"it_inventar/models"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Supplier"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// answer makes the next question of the console read the passed input
+func answer(t *testing.T, input string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, []byte(input+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = file
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		_ = file.Close()
+	})
+}
+
+// historyStep confirms and runs an undo or redo like the main menu
+func historyStep(t *testing.T, undo bool) {
+	t.Helper()
+	answer(t, "y")
+	handleHistoryStep(undo)
+}
+
+// restart simulates a new start of the application, only the files of the data directory are kept
+func restart() {
+	store = models.NewMemoryItemStore(store.GetAllItems())
+}
+
+// quantity returns the quantity of the item with the passed ID, -1 when it doesn't exist
+func quantity(t *testing.T, id int) int {
+	t.Helper()
+	item, err := store.GetItemByID(id)
+	if err != nil {
+		return -1
+	}
+	return item.Quantity
+}
+
+// setQuantity changes the quantity of the item as an undoable change
+func setQuantity(t *testing.T, id, quantity int) {
+	t.Helper()
+	err := saveUndoableItemChange("change quantity", func() error {
+		item, err := store.GetItemByID(id)
+		if err != nil {
+			return err
+		}
+		item.Quantity = quantity
+		return store.UpdateItem(id, item)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUndoAndRedoAcrossRestart(t *testing.T) {
+	useTestInventory(t, []models.Item{{ID: 1, ArticleName: "Monitor", ArticleNumber: "M001", Quantity: 3}})
+	setQuantity(t, 1, 7)
+
+	restart()
+	historyStep(t, true)
+	if got := quantity(t, 1); got != 3 {
+		t.Fatalf("quantity is %d after undo, want 3", got)
+	}
+
+	restart()
+	historyStep(t, false)
+	if got := quantity(t, 1); got != 7 {
+		t.Fatalf("quantity is %d after redo, want 7", got)
+	}
+	current, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current.Undo) != 1 || len(current.Redo) != 0 {
+		t.Errorf("history has %d undo and %d redo steps, want 1 and 0", len(current.Undo), len(current.Redo))
+	}
+}
+
+func TestUndoOfAddAndDelete(t *testing.T) {
+	useTestInventory(t, []models.Item{{ID: 1, ArticleName: "Monitor", ArticleNumber: "M001", Quantity: 3}})
+
+	var added int
+	err := saveUndoableItemChange("add Maus", func() error {
+		var err error
+		added, err = store.AddItem(models.Item{ArticleName: "Maus", ArticleNumber: "Z001", Quantity: 1})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	historyStep(t, true)
+	if _, err := store.GetItemByID(added); err == nil {
+		t.Fatal("the added item still exists after undo")
+	}
+
+	// An undone item is not resurrected by a new item, the new one gets a new ID
+	err = saveUndoableItemChange("add Tastatur", func() error {
+		id, err := store.AddItem(models.Item{ArticleName: "Tastatur", ArticleNumber: "Z002", Quantity: 1})
+		if err == nil && id == added {
+			t.Errorf("the new item got the ID %d of the undone item", id)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, _ := loadHistory()
+	if len(current.Redo) != 0 {
+		t.Errorf("a new change left %d redo steps", len(current.Redo))
+	}
+
+	if err := saveUndoableItemChange("delete Monitor", func() error { return store.RemoveItem(1) }); err != nil {
+		t.Fatal(err)
+	}
+	historyStep(t, true)
+	if item, _ := store.GetItemByID(1); item.IsDeleted || item.DeleteDate != nil {
+		t.Errorf("the item is still deleted after undo: %+v", item)
+	}
+	historyStep(t, false)
+	if item, _ := store.GetItemByID(1); !item.IsDeleted {
+		t.Error("the item is not deleted after redo")
+	}
+}
+
+func TestUndoLevels(t *testing.T) {
+	useTestInventory(t, []models.Item{{ID: 1, ArticleName: "Monitor", ArticleNumber: "M001", Quantity: 3}})
+	settings.UndoLevels = 2
+	for _, quantity := range []int{4, 5, 6} {
+		setQuantity(t, 1, quantity)
+	}
+
+	historyStep(t, true)
+	historyStep(t, true)
+	historyStep(t, true)
+	if got := quantity(t, 1); got != 4 {
+		t.Errorf("quantity is %d after undoing everything, want 4 as only 2 levels are kept", got)
+	}
+}
+
+func TestUndoAsksBeforeOverwritingLaterChanges(t *testing.T) {
+	useTestInventory(t, []models.Item{{ID: 1, ArticleName: "Monitor", ArticleNumber: "M001", Quantity: 3}})
+	setQuantity(t, 1, 7)
+
+	// Another change that is not on the undo stack, e.g. made by another user
+	item, _ := store.GetItemByID(1)
+	item.Quantity = 9
+	if err := store.UpdateItem(1, item); err != nil {
+		t.Fatal(err)
+	}
+	current, _ := loadHistory()
+	if conflicts := changedSince(current.Undo[0], true); !slices.Equal(conflicts, []string{"item 1"}) {
+		t.Errorf("changedSince reported %v, want [item 1]", conflicts)
+	}
+
+	// The undo is confirmed, the question whether to overwrite the later change gets no answer, which declines it
+	answer(t, "y")
+	handleHistoryStep(true)
+	if got := quantity(t, 1); got != 9 {
+		t.Errorf("quantity is %d, want the later change 9 to be kept", got)
+	}
+}
+
+func TestUndoOfReassignRestoresItemsAndList(t *testing.T) {
+	useTestInventory(t, []models.Item{
+		{ID: 1, ArticleName: "Monitor", ArticleNumber: "M001", Supplier: "Dell", Quantity: 3},
+		{ID: 2, ArticleName: "Maus", ArticleNumber: "Z001", Supplier: "Logitech", Quantity: 1},
+	})
+	suppliers := []models.Supplier{{SupplierName: "Dell", Email: "sales@dell.example"}, {SupplierName: "Logitech"}}
+	if err := Supplier.CreateSupplierFile(settings.SupplierPath(), suppliers); err != nil {
+		t.Fatal(err)
+	}
+
+	reassign := func() error {
+		return Supplier.DeleteSupplier(settings.SupplierPath(), "Dell", store, func(item *models.Item) {
+			item.SetReference(Audit.EntitySupplier, "Logitech")
+		})
+	}
+	if err := saveUndoableReferenceChange("delete supplier Dell", Audit.EntitySupplier, reassign, nil); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ := store.GetItemByID(1); item.Supplier != "Logitech" {
+		t.Fatalf("item has supplier %q after the reassignment, want Logitech", item.Supplier)
+	}
+
+	restart()
+	historyStep(t, true)
+	restored, err := Supplier.ReadSupplierRecords(settings.SupplierPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(restored, suppliers) {
+		t.Errorf("suppliers are %+v after undo, want %+v", restored, suppliers)
+	}
+	if item, _ := store.GetItemByID(1); item.Supplier != "Dell" {
+		t.Errorf("item has supplier %q after undo, want Dell", item.Supplier)
+	}
+
+	historyStep(t, false)
+	if names, _ := Supplier.ReadSuppliers(settings.SupplierPath()); !slices.Equal(names, []string{"Logitech"}) {
+		t.Errorf("suppliers are %v after redo, want [Logitech]", names)
+	}
+	if item, _ := store.GetItemByID(1); item.Supplier != "Logitech" {
+		t.Errorf("item has supplier %q after redo, want Logitech", item.Supplier)
+	}
+}
@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"fmt"
+	"it_inventar/models"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Location"
+	"it_inventar/views/console"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// handleLocations shows the locations with their stock and adds, renames or deletes them until the user goes back
+func handleLocations() {
+	filePath := settings.LocationsPath()
+	for {
+		locations, err := Location.ReadLocations(filePath)
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error reading locations: %v", err))
+			return
+		}
+		console.ShowLocations(locations, models.GetActiveItems(store.GetAllItems()))
+
+		action := strings.ToLower(console.AskForLocationAction())
+		if action == "c" {
+			return
+		}
+		if action == "a" {
+			console.ShowMessage("Enter the name of the location:")
+			name, err := settings.NameRules().Check(console.GetUserInput(), locations)
+			if err != nil {
+				console.ShowMessage(fmt.Sprintf("❌ Invalid location name: %v.", err))
+				continue
+			}
+			err = saveUndoableListChange(fmt.Sprintf("add location %s", name), Audit.EntityLocation, func() error {
+				return Location.AddLocation(filePath, name)
+			})
+			if err != nil {
+				console.ErrorMessage(fmt.Sprintf("❌ Error adding location: %v", err))
+				continue
+			}
+			console.ShowMessage(fmt.Sprintf("✅ Location '%s' added.", name))
+			continue
+		}
+		if action != "r" && action != "d" {
+			console.ShowMessage("❌ Invalid selection. Please try again.")
+			continue
+		}
+
+		if len(locations) == 0 {
+			console.ShowMessage("⚠️ No locations available yet.")
+			continue
+		}
+		console.ShowMessage("Enter the number of the location:")
+		index, err := strconv.Atoi(console.GetUserInput())
+		if err != nil || index < 1 || index > len(locations) {
+			console.ErrorMessage("Invalid input. Please enter a valid location number.")
+			continue
+		}
+		name := locations[index-1]
+		if action == "r" {
+			renameLocation(locations, name)
+		} else {
+			deleteLocation(name)
+		}
+	}
+}
+
+// renameLocation renames a location, the items with pieces there are changed in the same save
+func renameLocation(locations []string, oldName string) {
+	console.ShowMessage(fmt.Sprintf("Enter the new name of the location '%s':", oldName))
+	// The name itself doesn't count as a duplicate, so its case can be corrected
+	others := slices.DeleteFunc(slices.Clone(locations), func(name string) bool { return name == oldName })
+	newName, err := settings.NameRules().Check(console.GetUserInput(), others)
+	if err != nil {
+		console.ShowMessage(fmt.Sprintf("❌ Invalid location name: %v.", err))
+		return
+	}
+	if newName == oldName {
+		console.ShowMessage("⚠️ The name is unchanged.")
+		return
+	}
+	rename := func() error {
+		return Location.RenameLocation(settings.LocationsPath(), oldName, newName, store)
+	}
+	if err := saveUndoableReferenceChange(fmt.Sprintf("rename location %s to %s", oldName, newName), Audit.EntityLocation, rename, nil); err != nil {
+		console.ErrorMessage(fmt.Sprintf("❌ Error renaming location: %v", err))
+		return
+	}
+	console.ShowMessage(fmt.Sprintf("✅ '%s' renamed to '%s'.", oldName, newName))
+}
+
+// deleteLocation deletes a location after asking the user, the pieces still there are kept without location
+func deleteLocation(name string) {
+	stocked := models.ItemsAtLocation(store.GetAllItems(), name)
+	if len(stocked) > 0 {
+		console.ShowAllItems(stocked, false, console.LocationColumn(name))
+		console.ShowMessage(fmt.Sprintf("⚠️ %d item(s) have pieces at '%s', they will have no location. Continue? (y/n)", len(stocked), name))
+	} else {
+		console.ShowMessage(fmt.Sprintf("Delete the location '%s'? (y/n)", name))
+	}
+	if strings.ToLower(console.AskForInput()) != "y" {
+		console.ShowMessage(fmt.Sprintf("Location '%s' was kept.", name))
+		return
+	}
+
+	unassign := func() error {
+		items := models.ItemsAtLocation(store.GetAllItems(), name)
+		var failed error
+		err := store.UpdateItems(itemIds(items), func(item *models.Item) {
+			if err := item.Transfer(name, models.NoLocation, item.LocationQuantity(name)); err != nil {
+				failed = err
+			}
+		})
+		if failed != nil {
+			return failed
+		}
+		return err
+	}
+	deleteFromList := func() error {
+		return Location.DeleteLocation(settings.LocationsPath(), name)
+	}
+	if err := saveUndoableReferenceChange(fmt.Sprintf("delete location %s", name), Audit.EntityLocation, unassign, deleteFromList); err != nil {
+		console.ErrorMessage(fmt.Sprintf("❌ Error deleting location: %v", err))
+		return
+	}
+	console.ShowMessage(fmt.Sprintf("✅ Location '%s' deleted.", name))
+}
+
+// askForStockLocation asks where pieces of the item are put (taking false) or taken from (taking true).
+// Without locations, or when only one location has pieces to take, nothing is asked. False means the user canceled.
+func askForStockLocation(item models.Item, taking bool) (string, bool) {
+	locations, err := Location.ReadLocations(settings.LocationsPath())
+	if err != nil {
+		console.ShowError(err)
+	}
+	var options []string
+	if taking {
+		for _, location := range append([]string{models.NoLocation}, locations...) {
+			if item.LocationQuantity(location) > 0 {
+				options = append(options, location)
+			}
+		}
+		// Locations that were deleted from the list still count
+		for _, location := range slices.Sorted(maps.Keys(item.Locations)) {
+			if !slices.Contains(options, location) {
+				options = append(options, location)
+			}
+		}
+	} else {
+		options = append([]string{models.NoLocation}, locations...)
+	}
+	if len(options) == 0 {
+		return models.NoLocation, true
+	}
+	if len(options) == 1 {
+		return options[0], true
+	}
+
+	console.ShowStockLocations(item, options)
+	prompt := "Enter the number of the location the pieces are taken from (or 'C' to cancel):"
+	if !taking {
+		prompt = "Enter the number of the location the pieces are put at, [Enter] for no location (or 'C' to cancel):"
+	}
+	for {
+		console.ShowMessage(prompt)
+		input := console.AskForInput()
+		switch {
+		case strings.ToLower(input) == "c":
+			return "", false
+		case input == "" && !taking:
+			return models.NoLocation, true
+		}
+		index, err := strconv.Atoi(input)
+		if err == nil && index >= 1 && index <= len(options) {
+			return options[index-1], true
+		}
+		console.MessageGeneralInvalidID()
+	}
+}
+
+// handleTransferStock moves pieces of the item in stock from one location to another
+func handleTransferStock(id int) {
+	console.Clear()
+	item, err := store.GetItemByID(id)
+	if err != nil {
+		console.ShowError(err)
+		return
+	}
+	locations, err := Location.ReadLocations(settings.LocationsPath())
+	if err != nil {
+		console.ErrorMessage(fmt.Sprintf("❌ Error reading locations: %v", err))
+		return
+	}
+	if len(locations) == 0 {
+		console.ShowMessage("⚠️ No locations available yet. Locations are added in the service menu.")
+		return
+	}
+	if item.Quantity == 0 {
+		console.ShowMessage(fmt.Sprintf("⚠️ No piece of %s is in stock.", item.ArticleName))
+		return
+	}
+
+	from, ok := askForStockLocation(item, true)
+	if !ok {
+		return
+	}
+	console.ShowMessage(fmt.Sprintf("%d pieces are at %s.", item.LocationQuantity(from), models.LocationName(from)))
+	var targets []string
+	for _, location := range append([]string{models.NoLocation}, locations...) {
+		if location != from {
+			targets = append(targets, location)
+		}
+	}
+	console.ShowStockLocations(item, targets)
+	console.ShowMessage("Enter the number of the location the pieces go to:")
+	index, err := strconv.Atoi(console.AskForInput())
+	if err != nil || index < 1 || index > len(targets) {
+		console.MessageGeneralInvalidID()
+		return
+	}
+	to := targets[index-1]
+	console.ShowMessage("Enter the quantity to transfer:")
+	count := console.AskForQuantity(0, false)
+
+	err = saveUndoableItemChange(fmt.Sprintf("transfer %d pieces of %s from %s to %s", count, item.ArticleName, models.LocationName(from), models.LocationName(to)), func() error {
+		current, err := store.GetItemByID(id)
+		if err != nil {
+			return err
+		}
+		if err := current.Transfer(from, to, count); err != nil {
+			return err
+		}
+		item = current
+		return store.UpdateItem(id, current)
+	})
+	if err != nil {
+		console.ShowMessage(fmt.Sprintf("❌ %v", err))
+		return
+	}
+	console.ShowMessage(fmt.Sprintf("Now %d pieces at %s and %d at %s.", item.LocationQuantity(from), models.LocationName(from), item.LocationQuantity(to), models.LocationName(to)))
+	console.ShowMessage("✅ Stock successfully transferred!")
+}
+
+// filterByLocation asks for a location when there are any and returns the items with pieces there,
+// together with the column that shows the stock at the location
+func filterByLocation(items []models.Item) ([]models.Item, []console.Column) {
+	locations, err := Location.ReadLocations(settings.LocationsPath())
+	if err != nil {
+		console.ShowError(err)
+		return items, nil
+	}
+	if len(locations) == 0 {
+		return items, nil
+	}
+	console.ShowLocationFilterOptions(len(items), locations)
+	input := console.AskForInput()
+	index, err := strconv.Atoi(input)
+	if input == "" || err != nil || index < 1 || index > len(locations) {
+		return items, []console.Column{console.LocationsColumn()}
+	}
+	return models.ItemsAtLocation(items, locations[index-1]), []console.Column{console.LocationColumn(locations[index-1])}
+}
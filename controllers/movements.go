@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"fmt"
+	"it_inventar/models"
+	"it_inventar/models/Ledger"
+	"it_inventar/views/console"
+	"strings"
+)
+
+// openingBalanceComment marks the movement that brings the stock an item had before its first booking into the ledger
+const openingBalanceComment = "opening balance"
+
+// recordMovements writes a movement for every item whose quantity differs before and after a change, with the
+// reason, location, reference and comment of the booking. An item booked for the first time gets an opening balance
+// with the stock it had before, so its movements add up to its quantity.
+func recordMovements(before, after []models.Item, booking Ledger.Movement) {
+	previous := map[int]int{}
+	for _, item := range before {
+		previous[item.ID] = item.Quantity
+	}
+	var changed []models.Item
+	for _, item := range after {
+		if item.Quantity != previous[item.ID] {
+			changed = append(changed, item)
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	ledger, err := Ledger.ReadMovements(settings.MovementsPath(), 0)
+	if err == nil {
+		booked := map[int]bool{}
+		for _, movement := range ledger {
+			booked[movement.ItemID] = true
+		}
+		var movements []Ledger.Movement
+		for _, item := range changed {
+			if !booked[item.ID] && previous[item.ID] > 0 {
+				movements = append(movements, Ledger.Movement{ItemID: item.ID, Amount: previous[item.ID], Reason: Ledger.ReasonCorrection, Comment: openingBalanceComment})
+			}
+			movement := booking
+			movement.ItemID = item.ID
+			movement.Amount = item.Quantity - previous[item.ID]
+			// A booking that changed the stock the other way, e.g. a reloaded change of another user, is a correction
+			if movement.Validate() != nil {
+				movement.Reason = Ledger.ReasonCorrection
+			}
+			movements = append(movements, movement)
+		}
+		err = Ledger.Record(settings.MovementsPath(), movements)
+	}
+	if err != nil {
+		console.ErrorMessage(fmt.Sprintf("❌ The change was saved, but its stock movement couldn't be recorded: %v", err))
+	}
+}
+
+// handleMovementHistory shows the movements of the item with the running stock, newest last. When the movements
+// don't add up to the quantity, e.g. for stock from before the ledger, a correction can be booked.
+func handleMovementHistory(id int) {
+	console.Clear()
+	item, err := store.GetItemByID(id)
+	if err != nil {
+		console.ShowError(err)
+		return
+	}
+	movements, err := Ledger.ReadMovements(settings.MovementsPath(), id)
+	if err != nil {
+		console.ErrorMessage(fmt.Sprintf("❌ Error reading the movements: %v", err))
+		return
+	}
+
+	console.ShowMessage(fmt.Sprintf("* Movements of %s (ID %d) *", item.ArticleName, item.ID))
+	if len(movements) == 0 {
+		console.ShowMessage("  No movements recorded yet.")
+	}
+	for page := console.InitialPage; len(movements) > 0; page++ {
+		start, end := console.PageIndexCalculate(page, console.PageSize, len(movements))
+		console.ShowMovements(movements[start:end], Ledger.Balance(movements[:start]))
+		if end == len(movements) {
+			break
+		}
+		if strings.ToLower(console.GetPageInput()) == "c" {
+			return
+		}
+	}
+
+	difference := item.Quantity - Ledger.Balance(movements)
+	if difference == 0 {
+		console.ShowMessage(fmt.Sprintf("✅ The movements add up to the stock of %d pieces.", item.Quantity))
+		return
+	}
+	console.ShowMessage(fmt.Sprintf("⚠️ The movements add up to %d pieces, but %d are in stock.", Ledger.Balance(movements), item.Quantity))
+	console.ShowMessage(fmt.Sprintf("Record a correction of %+d pieces, so the movements match the stock? (y/n)", difference))
+	if strings.ToLower(console.AskForInput()) != "y" {
+		return
+	}
+	comment := "reconciliation with the stock"
+	if len(movements) == 0 {
+		comment = openingBalanceComment
+	}
+	correction := Ledger.Movement{ItemID: id, Amount: difference, Reason: Ledger.ReasonCorrection, Comment: comment}
+	if err := Ledger.Record(settings.MovementsPath(), []Ledger.Movement{correction}); err != nil {
+		console.ErrorMessage(fmt.Sprintf("❌ Error recording the correction: %v", err))
+		return
+	}
+	console.ShowMessage("✅ Correction recorded.")
+}
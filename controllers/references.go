@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"fmt"
+	"it_inventar/models"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Category"
+	"it_inventar/models/Supplier"
+	"it_inventar/views/console"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// deleteListEntry deletes a category or supplier. When items still refer to it, the user decides whether the
+// deletion is blocked, the items are reassigned to another entry or the items are deleted as well.
+// It returns false when the entry was kept.
+func deleteListEntry(kind, name string) (bool, error) {
+	// The items are changed in the same transaction as the list, so they never refer to a deleted entry
+	deleteFromList := func(update func(item *models.Item)) func() error {
+		return func() error {
+			if kind == Audit.EntityCategory {
+				return Category.DeleteCategory(settings.CategoriesPath(), name, store, update)
+			}
+			return Supplier.DeleteSupplier(settings.SupplierPath(), name, store, update)
+		}
+	}
+	description := fmt.Sprintf("delete %s %s", kind, name)
+
+	referencing := models.ItemsReferencing(store.GetAllItems(), kind, name)
+	if len(referencing) == 0 {
+		return true, saveUndoableListChange(description, kind, deleteFromList(nil))
+	}
+
+	console.ShowReferencingItems(kind, name, referencing)
+	switch strings.ToLower(console.AskForReferenceResolution(kind)) {
+	case "r":
+		list, err := readList(kind)
+		if err != nil {
+			return false, err
+		}
+		others := slices.DeleteFunc(list, func(entry string) bool { return entry == name })
+		if len(others) == 0 {
+			console.ShowMessage(fmt.Sprintf("⚠️ There is no other %s to reassign the items to.", kind))
+			return false, nil
+		}
+		replacement := console.SelectItem(others, console.PageSize, capitalize(kind))
+		if replacement == "C" || replacement == "" {
+			return false, nil
+		}
+		reassign := deleteFromList(func(item *models.Item) { item.SetReference(kind, replacement) })
+		return true, saveUndoableReferenceChange(fmt.Sprintf("%s, items moved to %s", description, replacement), kind, reassign, nil)
+	case "d":
+		console.ShowMessage(fmt.Sprintf("⚠️ %d item(s) will be marked as deleted. Continue? (y/n)", len(referencing)))
+		if strings.ToLower(console.AskForInput()) != "y" {
+			return false, nil
+		}
+		now := time.Now()
+		cascade := deleteFromList(func(item *models.Item) {
+			item.IsDeleted = true
+			item.DeleteDate = &now
+		})
+		return true, saveUndoableReferenceChange(fmt.Sprintf("%s with its items", description), kind, cascade, nil)
+	default:
+		return false, nil
+	}
+}
+
+// handleReferenceCheck warns about items that refer to categories or suppliers which are not in the lists
+// and offers to add the missing names to the lists
+func handleReferenceCheck() {
+	categories, err := Category.ReadCategories(settings.CategoriesPath())
+	if err != nil {
+		console.ShowError(err)
+		return
+	}
+	suppliers, err := Supplier.ReadSuppliers(settings.SupplierPath())
+	if err != nil {
+		console.ShowError(err)
+		return
+	}
+	issues := models.CheckReferences(store.GetAllItems(), categories, suppliers)
+	if len(issues) == 0 {
+		return
+	}
+
+	console.ShowReferenceIssues(issues)
+	if strings.ToLower(console.AskForInput()) != "a" {
+		return
+	}
+	for _, kind := range []string{Audit.EntityCategory, Audit.EntitySupplier} {
+		var missing []string
+		for _, issue := range issues {
+			if issue.Kind == kind && !slices.Contains(missing, issue.Name) {
+				missing = append(missing, issue.Name)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		err := saveUndoableListChange(fmt.Sprintf("add missing %s names", kind), kind, func() error {
+			for _, name := range missing {
+				add := Supplier.AddSupplierToFile
+				if kind == Audit.EntityCategory {
+					add = Category.AddCategoryToFile
+				}
+				if err := add(listPath(kind), name); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			console.ShowError(err)
+			return
+		}
+	}
+	console.ShowMessage("✅ The missing names were added.")
+	console.ShowContinue()
+}
+
+// listPath returns the path of the category or the supplier file
+func listPath(kind string) string {
+	if kind == Audit.EntityCategory {
+		return settings.CategoriesPath()
+	}
+	return settings.SupplierPath()
+}
+
+// itemIds returns the IDs of the passed items
+func itemIds(items []models.Item) []int {
+	ids := make([]int, len(items))
+	for index, item := range items {
+		ids[index] = item.ID
+	}
+	return ids
+}
+
+// capitalize returns the word with an upper case first letter, used for the item type shown by the console
+func capitalize(word string) string {
+	if word == "" {
+		return word
+	}
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+// handleRenameListEntry renames a category or supplier, the items that use it are renamed in the same save
+func handleRenameListEntry(kind string) {
+	for {
+		var list []string
+		var categories []Category.Node
+		var err error
+		if kind == Audit.EntityCategory {
+			categories, err = readCategoryTree()
+			list = Category.NodeNames(categories)
+		} else {
+			list, err = readList(kind)
+		}
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error reading the %s list: %v", kind, err))
+			return
+		}
+		if len(list) == 0 {
+			console.ShowMessage(fmt.Sprintf("No %s available to rename.", kind))
+			return
+		}
+		if kind == Audit.EntityCategory {
+			console.ShowCategoriesList(categories)
+		} else {
+			console.ShowSuppliersList(list)
+		}
+
+		console.ShowMessage(fmt.Sprintf("Enter the number of the %s you want to rename (or 'C' to cancel):", kind))
+		input := console.GetUserInput()
+		if strings.ToLower(input) == "c" {
+			console.ShowMessage("Action canceled. Returning to the service menu...")
+			return
+		}
+		index, err := strconv.Atoi(input)
+		if err != nil || index < 1 || index > len(list) {
+			console.ErrorMessage(fmt.Sprintf("Invalid input. Please enter a valid %s number.", kind))
+			continue
+		}
+		oldName := list[index-1]
+
+		console.ShowMessage(fmt.Sprintf("Enter the new name of the %s '%s':", kind, oldName))
+		// The name itself doesn't count as a duplicate, so its case can be corrected
+		others := slices.DeleteFunc(slices.Clone(list), func(name string) bool { return name == oldName })
+		newName, err := settings.NameRules().Check(console.GetUserInput(), others)
+		if err != nil {
+			console.ShowMessage(fmt.Sprintf("❌ Invalid %s name: %v.", kind, err))
+			continue
+		}
+		if newName == oldName {
+			console.ShowMessage("⚠️ The name is unchanged.")
+			continue
+		}
+
+		count := len(models.AllItemsReferencing(store.GetAllItems(), kind, oldName))
+		console.ShowMessage(fmt.Sprintf("Rename '%s' to '%s' and update %d item(s)? (y/n)", oldName, newName, count))
+		if strings.ToLower(console.AskForInput()) != "y" {
+			console.HandleChancelAction()
+			continue
+		}
+
+		rename := func() error {
+			if kind == Audit.EntityCategory {
+				return Category.RenameCategory(settings.CategoriesPath(), oldName, newName, store, settings.AttributesPath())
+			}
+			return Supplier.RenameSupplier(settings.SupplierPath(), oldName, newName, store)
+		}
+		err = saveUndoableReferenceChange(fmt.Sprintf("rename %s %s to %s", kind, oldName, newName), kind, rename, nil)
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error renaming %s: %v", kind, err))
+			continue
+		}
+		console.ShowMessage(fmt.Sprintf("✅ '%s' renamed to '%s'.", oldName, newName))
+	}
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"it_inventar/models/Audit"
 	"it_inventar/views/console"
 	"strings"
 )
@@ -17,8 +18,22 @@ func executeCommand() {
 		handleChangeQuantity()
 	case "4":
 		handleChanceArticleInformation()
+	case "5":
+		handleCheckOut()
+	case "6":
+		handleCheckIn()
+	case "7":
+		handleShowAssignments()
+	case "8":
+		handleViewItemsByCategory()
 	case "9":
 		handleViewItems()
+	case "10":
+		handleBelowMinimum()
+	case "U":
+		handleUndo()
+	case "R":
+		handleRedo()
 	case "4600":
 		console.Clear()
 		hiddenCommand()
@@ -63,16 +78,38 @@ func hiddenCommand() {
 			handleAddSuppliers()
 		case "3":
 			handleDeleteSupplier()
+		case "4":
+			handleRenameListEntry(Audit.EntitySupplier)
+		case "5":
+			handleEditSupplierDetails()
 		case "11":
 			handleShowCategories()
 		case "12":
 			handleAddCategories()
 		case "13":
 			handleDeleteCategories()
+		case "14":
+			handleRenameListEntry(Audit.EntityCategory)
+		case "15":
+			handleMoveCategory()
+		case "16":
+			handleCategoryAttributes()
+		case "17":
+			handleNumberPatterns()
+		case "18":
+			handleStockLevels()
+		case "21":
+			handleEmployees()
+		case "31":
+			handleLocations()
 		case "ID":
 			handleViewDeletedItems()
 		case "IA":
 			handleViewAllItems()
+		case "B":
+			handleBackups()
+		case "AL":
+			handleAuditLog()
 		case "C":
 			console.Clear()
 			console.ShowMessage("🔙 Exiting the Hidden Command Menu...")
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"fmt"
+	"it_inventar/config"
+	"it_inventar/models"
+	"it_inventar/models/Category"
+	"it_inventar/models/Supplier"
+	"it_inventar/views/console"
+	"os"
+	"strings"
+)
+
+// dataFile is one of the files the inventory needs, with the function that creates it
+type dataFile struct {
+	path   string
+	create func(mode string) error
+}
+
+// requiredDataFiles returns the files the application works with
+func requiredDataFiles() []dataFile {
+	return []dataFile{
+		{path: settings.DataPath(), create: func(string) error {
+			return models.CreateDataFile(settings.DataPath())
+		}},
+		{path: settings.CategoriesPath(), create: func(mode string) error {
+			var categories []string
+			if mode == config.InitDefaults {
+				categories = Category.DefaultCategories
+			}
+			return Category.CreateCategoryFile(settings.CategoriesPath(), categories)
+		}},
+		{path: settings.SupplierPath(), create: func(string) error {
+			return Supplier.CreateSupplierFile(settings.SupplierPath(), nil)
+		}},
+	}
+}
+
+// handleFirstRun creates missing data files, either as chosen with the -init flag or after asking the user.
+// It returns false when the user prefers to quit instead.
+func handleFirstRun() bool {
+	var missing []dataFile
+	for _, file := range requiredDataFiles() {
+		if _, err := os.Stat(file.path); os.IsNotExist(err) {
+			missing = append(missing, file)
+		}
+	}
+	if len(missing) == 0 {
+		return true
+	}
+
+	mode := settings.InitMode
+	for mode == "" {
+		var missingPaths []string
+		for _, file := range missing {
+			missingPaths = append(missingPaths, file.path)
+		}
+		console.ShowFirstRunMenu(missingPaths)
+
+		switch strings.ToLower(console.AskForInput()) {
+		case "1":
+			mode = config.InitEmpty
+		case "2":
+			mode = config.InitDefaults
+		case "q":
+			return false
+		default:
+			console.ShowMessage("❌ Invalid selection. Please try again.")
+		}
+	}
+
+	for _, file := range missing {
+		if err := file.create(mode); err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error creating %s: %v", file.path, err))
+			return false
+		}
+		console.ShowMessage(fmt.Sprintf("✅ Created %s", file.path))
+	}
+	return true
+}
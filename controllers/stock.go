@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"fmt"
+	"it_inventar/models"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Category"
+	"it_inventar/views/console"
+	"strconv"
+	"strings"
+)
+
+// stockLevels returns a function with the stock level of an item, its own or the default of its category.
+// The categories are read once, so the function can be used for a whole list.
+func stockLevels() (func(item models.Item) models.StockLevel, error) {
+	categories, err := Category.ReadCategoryRecords(settings.CategoriesPath())
+	levelOf := func(item models.Item) models.StockLevel {
+		return Category.ItemStockLevel(categories, item)
+	}
+	return levelOf, err
+}
+
+// itemsBelowMinimum returns the items below their minimum stock, it marks them in the item lists.
+// Without a category file only the minimums of the items themselves count.
+func itemsBelowMinimum(items []models.Item) []models.Item {
+	levelOf, _ := stockLevels()
+	return models.ItemsBelowMinimum(items, levelOf)
+}
+
+// categoryStockLevel returns the stock level that applies to the items of the category without a level of their own
+func categoryStockLevel(category string) models.StockLevel {
+	categories, err := Category.ReadCategoryRecords(settings.CategoriesPath())
+	if err != nil {
+		console.ShowError(err)
+	}
+	level, _ := Category.StockLevelFor(categories, category)
+	return level
+}
+
+// stockLevelSummary describes the stock level of an item for the review of its details, empty when it is not watched
+// and has no level of its own
+func stockLevelSummary(level models.StockLevel, category string) string {
+	if level.IsDefined() {
+		return level.String()
+	}
+	if inherited := categoryStockLevel(category); inherited.IsSet() {
+		return fmt.Sprintf("%s (category default)", inherited)
+	}
+	return ""
+}
+
+// Case 10
+// handleBelowMinimum shows the articles below their minimum stock with the pieces to order
+func handleBelowMinimum() {
+	levelOf, err := stockLevels()
+	if err != nil {
+		console.ErrorMessage(fmt.Sprintf("❌ Error reading categories: %v", err))
+		return
+	}
+	below := models.ItemsBelowMinimum(store.GetAllItems(), levelOf)
+	if len(below) == 0 {
+		console.Clear()
+		console.ShowExecuteCommandMenu()
+		console.ShowMessage("✅ No article is below its minimum stock.")
+		return
+	}
+	console.HandleViewItemsGeneric(below, false, console.StockLevelColumns(levelOf)...)
+}
+
+// warnBelowMinimum warns when a booking took the stock of the item from before pieces below its minimum
+func warnBelowMinimum(item models.Item, before int) {
+	if item.Quantity >= before {
+		return
+	}
+	levelOf, _ := stockLevels()
+	level := levelOf(item)
+	if !item.IsBelow(level) {
+		return
+	}
+	console.ShowMessage(fmt.Sprintf("⚠️ %s is below its minimum stock of %d pieces, please order %d pieces.", item.ArticleName, level.Minimum, level.OrderQuantity(item)))
+}
+
+// handleStockLevels lets the user define the default minimum and reorder quantity of the items of a category
+func handleStockLevels() {
+	for {
+		records, err := Category.ReadCategoryRecords(settings.CategoriesPath())
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error reading categories: %v", err))
+			return
+		}
+		categories := Category.Tree(records)
+		if len(categories) == 0 {
+			console.ShowNoCategoriesMessage()
+			return
+		}
+		console.ShowCategoriesList(categories)
+
+		console.ShowMessage("Enter the number of the category whose minimum stock you want to define (or 'C' to cancel):")
+		input := console.GetUserInput()
+		if strings.ToLower(input) == "c" {
+			console.ShowMessage("Action canceled. Returning to the service menu...")
+			return
+		}
+		index, err := strconv.Atoi(input)
+		if err != nil || index < 1 || index > len(categories) {
+			console.ErrorMessage("Invalid input. Please enter a valid category number.")
+			continue
+		}
+		categoryName := categories[index-1].CategoryName
+
+		// The level of the parent applies when the category has none of its own
+		level, definedIn := Category.StockLevelFor(records, categoryName)
+		var inherited models.StockLevel
+		switch {
+		case definedIn == "":
+			console.ShowMessage(fmt.Sprintf("'%s' has no minimum stock, its articles are not watched.", categoryName))
+		case definedIn != categoryName && !level.IsSet():
+			console.ShowMessage(fmt.Sprintf("'%s' has no minimum stock like '%s', its articles are not watched.", categoryName, definedIn))
+			level = models.StockLevel{}
+		case definedIn != categoryName:
+			console.ShowMessage(fmt.Sprintf("'%s' uses the %s of '%s', a minimum of its own replaces it.", categoryName, level, definedIn))
+			inherited, level = level, models.StockLevel{}
+		default:
+			console.ShowMessage(fmt.Sprintf("Current stock level of '%s': %s", categoryName, level))
+		}
+
+		level = console.AskForStockLevel(level, inherited)
+		err = saveUndoableListChange(fmt.Sprintf("set minimum stock of category %s", categoryName), Audit.EntityCategory, func() error {
+			return Category.SetStockLevel(settings.CategoriesPath(), categoryName, level)
+		})
+		if err != nil {
+			console.ErrorMessage(fmt.Sprintf("❌ Error saving the minimum stock: %v", err))
+			continue
+		}
+		if !level.IsDefined() {
+			console.ShowMessage(fmt.Sprintf("✅ '%s' has no minimum stock of its own.", categoryName))
+			continue
+		}
+		if !level.IsSet() {
+			console.ShowMessage(fmt.Sprintf("✅ The articles of '%s' are not watched.", categoryName))
+			continue
+		}
+		below := itemsBelowMinimum(store.GetAllItems())
+		console.ShowMessage(fmt.Sprintf("✅ Articles of '%s': %s. %d article(s) are below their minimum stock now.", categoryName, level, len(below)))
+	}
+}
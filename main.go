@@ -1,7 +1,25 @@
 package main
 
-import "it_inventar/controllers"
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"it_inventar/config"
+	"it_inventar/controllers"
+	"it_inventar/models"
+	"os"
+)
 
 func main() {
-	controllers.Run()
+	settings, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Println("❌ Error loading the configuration:", err)
+		os.Exit(2)
+	}
+	store := models.NewCsvItemStore(settings.DataPath())
+	store.NumbersIncludeDeleted = settings.UniqueNumbersIncludeDeleted
+	controllers.Run(settings, store)
 }
@@ -0,0 +1,392 @@
+package Attribute
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Storage"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of attribute values
+const (
+	TypeText    = "text"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeYesNo   = "yes/no"
+	TypeDate    = "date"
+	TypeChoice  = "choice"
+)
+
+// Types are all attribute types in the order they are offered
+var Types = []string{TypeText, TypeInteger, TypeNumber, TypeYesNo, TypeDate, TypeChoice}
+
+// DateFormat is the format of date values, as they are entered and stored
+const DateFormat = "02.01.2006"
+
+// optionSeparator separates the options of a choice in the attribute file
+const optionSeparator = "|"
+
+// columns are the columns of the attribute file, in the order they are written
+var columns = []string{"Category", "Name", "Type", "Options", "Required"}
+
+// Definition is an attribute the items of a category have. Subcategories have the attributes of their parents as well.
+type Definition struct {
+	Category string
+	Name     string
+	Type     string
+	// Options are the values a choice can take, other types have none
+	Options  []string
+	Required bool
+}
+
+// Key identifies the definition in the audit log
+func (d Definition) Key() string {
+	return d.Category + ": " + d.Name
+}
+
+// Describe returns the name with the type, e.g. "Resolution (choice: FHD, QHD, 4K, required)"
+func (d Definition) Describe() string {
+	description := d.Type
+	if d.Type == TypeChoice {
+		description += ": " + strings.Join(d.Options, ", ")
+	}
+	if d.Type == TypeDate {
+		description += ", DD.MM.YYYY"
+	}
+	if d.Required {
+		description += ", required"
+	}
+	return fmt.Sprintf("%s (%s)", d.Name, description)
+}
+
+// Normalize checks the value against the type and returns it in the form it is stored in
+func (d Definition) Normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		if d.Required {
+			return "", fmt.Errorf("%s is required", d.Name)
+		}
+		return "", nil
+	}
+
+	switch d.Type {
+	case TypeInteger:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a whole number", d.Name)
+		}
+		return strconv.Itoa(number), nil
+	case TypeNumber:
+		number, err := parseNumber(value)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a number", d.Name)
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case TypeYesNo:
+		switch strings.ToLower(value) {
+		case "y", "yes", "j", "ja", "true":
+			return "yes", nil
+		case "n", "no", "nein", "false":
+			return "no", nil
+		}
+		return "", fmt.Errorf("%s must be yes or no", d.Name)
+	case TypeDate:
+		date, err := time.Parse(DateFormat, value)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a date like 31.12.2024", d.Name)
+		}
+		return date.Format(DateFormat), nil
+	case TypeChoice:
+		for _, option := range d.Options {
+			if strings.EqualFold(option, value) {
+				return option, nil
+			}
+		}
+		return "", fmt.Errorf("%s must be one of %s", d.Name, strings.Join(d.Options, ", "))
+	}
+	return value, nil
+}
+
+// Matches reports whether a stored value passes the filter. Integers, numbers and dates can be compared with
+// a leading =, <, <=, > or >=, text matches when it contains the filter, other types must be equal.
+// Case is ignored throughout.
+func (d Definition) Matches(value, filter string) bool {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return true
+	}
+	if value == "" {
+		return false
+	}
+
+	switch d.Type {
+	case TypeInteger, TypeNumber, TypeDate:
+		operator, operand := splitOperator(filter)
+		var comparison int
+		if d.Type == TypeDate {
+			left, leftErr := time.Parse(DateFormat, value)
+			right, rightErr := time.Parse(DateFormat, operand)
+			if leftErr != nil || rightErr != nil {
+				return false
+			}
+			comparison = left.Compare(right)
+		} else {
+			left, leftErr := parseNumber(value)
+			right, rightErr := parseNumber(operand)
+			if leftErr != nil || rightErr != nil {
+				return false
+			}
+			comparison = cmp.Compare(left, right)
+		}
+		switch operator {
+		case "<":
+			return comparison < 0
+		case "<=":
+			return comparison <= 0
+		case ">":
+			return comparison > 0
+		case ">=":
+			return comparison >= 0
+		}
+		return comparison == 0
+	case TypeText:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(filter))
+	case TypeYesNo:
+		normalized, err := d.Normalize(filter)
+		return err == nil && normalized == value
+	}
+	return strings.EqualFold(value, filter)
+}
+
+// ForCategory returns the attributes of a category, path is the category with its parents, the top level first.
+// The attributes of the parents come first.
+func ForCategory(definitions []Definition, path []string) []Definition {
+	var found []Definition
+	for _, category := range path {
+		for _, definition := range definitions {
+			if definition.Category == category {
+				found = append(found, definition)
+			}
+		}
+	}
+	return found
+}
+
+// ReadDefinitions reads all attribute definitions from the CSV file, a missing file defines no attributes
+func ReadDefinitions(filePath string) ([]Definition, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := file.Close()
+		if closeErr != nil {
+			fmt.Printf("Error closing file: %v\n", closeErr)
+		}
+	}()
+
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var definitions []Definition
+	for index, record := range records {
+		if index == 0 || len(record) < len(columns) {
+			continue // Header row or an incomplete line
+		}
+		definition := Definition{
+			Category: strings.TrimSpace(record[0]),
+			Name:     strings.TrimSpace(record[1]),
+			Type:     strings.TrimSpace(record[2]),
+			Required: strings.TrimSpace(record[4]) == "true",
+		}
+		if options := strings.TrimSpace(record[3]); options != "" {
+			definition.Options = strings.Split(options, optionSeparator)
+		}
+		if !slices.Contains(Types, definition.Type) {
+			definition.Type = TypeText
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, nil
+}
+
+// AddDefinition adds an attribute to a category, the name must not be used by the category yet
+func AddDefinition(filePath string, definition Definition) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	definitions, err := ReadDefinitions(filePath)
+	if err != nil {
+		return err
+	}
+	if index(definitions, definition.Category, definition.Name) >= 0 {
+		return fmt.Errorf("the category '%s' already has an attribute '%s'", definition.Category, definition.Name)
+	}
+	if err := overwriteDefinitionFile(filePath, append(definitions, definition)); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, []Audit.Entry{{Operation: Audit.OpAdd, Entity: Audit.EntityAttribute, Key: definition.Key(), Field: "Type", After: definition.Describe()}})
+}
+
+// DeleteDefinition removes an attribute from a category. The values the items have for it are kept,
+// they show up again when the attribute is added back.
+func DeleteDefinition(filePath, category, name string) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	definitions, err := ReadDefinitions(filePath)
+	if err != nil {
+		return err
+	}
+	position := index(definitions, category, name)
+	if position < 0 {
+		return fmt.Errorf("attribute '%s' of category '%s' no longer exists, it was probably changed by another user", name, category)
+	}
+	deleted := definitions[position]
+	if err := overwriteDefinitionFile(filePath, slices.Delete(definitions, position, position+1)); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, []Audit.Entry{{Operation: Audit.OpDelete, Entity: Audit.EntityAttribute, Key: deleted.Key(), Field: "Type", Before: deleted.Describe()}})
+}
+
+// SetDefinitions replaces all attribute definitions, added and removed attributes are recorded in the audit log
+func SetDefinitions(filePath string, definitions []Definition) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	_, entries, err := SetDefinitionsWrite(filePath, definitions)
+	if err != nil {
+		return err
+	}
+	if err := overwriteDefinitionFile(filePath, definitions); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, entries)
+}
+
+// SetDefinitionsWrite returns the write that replaces all attribute definitions and the audit entries of the change,
+// so they can be saved in the same transaction as the items. The caller holds the lock of the attribute file.
+func SetDefinitionsWrite(filePath string, definitions []Definition) (Storage.FileWrite, []Audit.Entry, error) {
+	current, err := ReadDefinitions(filePath)
+	if err != nil {
+		return Storage.FileWrite{}, nil, err
+	}
+	write := Storage.FileWrite{Path: filePath, Write: func(w io.Writer) error {
+		return writeDefinitions(w, definitions)
+	}}
+
+	var entries []Audit.Entry
+	for _, definition := range current {
+		if !slices.ContainsFunc(definitions, definition.Equal) {
+			entries = append(entries, Audit.Entry{Operation: Audit.OpDelete, Entity: Audit.EntityAttribute, Key: definition.Key(), Field: "Type", Before: definition.Describe()})
+		}
+	}
+	for _, definition := range definitions {
+		if !slices.ContainsFunc(current, definition.Equal) {
+			entries = append(entries, Audit.Entry{Operation: Audit.OpAdd, Entity: Audit.EntityAttribute, Key: definition.Key(), Field: "Type", After: definition.Describe()})
+		}
+	}
+	return write, entries, nil
+}
+
+// Equal reports whether both definitions are the same in every field
+func (d Definition) Equal(other Definition) bool {
+	return d.Category == other.Category && d.Name == other.Name && d.Type == other.Type &&
+		slices.Equal(d.Options, other.Options) && d.Required == other.Required
+}
+
+// RenameCategoryWrites returns the write that moves the attributes of a renamed category to its new name, so it is
+// saved in the same transaction as the category and its items. Without attributes of the category nothing is written.
+// The caller holds the lock of the attribute file.
+func RenameCategoryWrites(filePath, oldName, newName string) ([]Storage.FileWrite, error) {
+	definitions, err := ReadDefinitions(filePath)
+	if err != nil {
+		return nil, err
+	}
+	renamed := false
+	for position := range definitions {
+		if definitions[position].Category == oldName {
+			definitions[position].Category = newName
+			renamed = true
+		}
+	}
+	if !renamed {
+		return nil, nil
+	}
+	return []Storage.FileWrite{{Path: filePath, Write: func(w io.Writer) error {
+		return writeDefinitions(w, definitions)
+	}}}, nil
+}
+
+// overwriteDefinitionFile replaces the attribute file atomically after keeping a snapshot of it
+func overwriteDefinitionFile(filePath string, definitions []Definition) error {
+	if err := Storage.TakeSnapshot(filePath); err != nil {
+		return err
+	}
+	return Storage.WriteFileAtomic(filePath, func(w io.Writer) error {
+		return writeDefinitions(w, definitions)
+	})
+}
+
+// writeDefinitions writes the header row and one attribute definition per row
+func writeDefinitions(w io.Writer, definitions []Definition) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, definition := range definitions {
+		record := []string{definition.Category, definition.Name, definition.Type, strings.Join(definition.Options, optionSeparator), strconv.FormatBool(definition.Required)}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// index returns the position of the attribute of the category, the name is compared case-insensitively
+func index(definitions []Definition, category, name string) int {
+	return slices.IndexFunc(definitions, func(definition Definition) bool {
+		return definition.Category == category && strings.EqualFold(definition.Name, name)
+	})
+}
+
+// parseNumber parses a decimal number, a decimal comma is accepted as well
+func parseNumber(value string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(strings.TrimSpace(value), ",", ".", 1), 64)
+}
+
+// splitOperator splits a filter like ">= 27" into its comparison operator and the value
+func splitOperator(filter string) (string, string) {
+	for _, operator := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(filter, operator) {
+			return operator, strings.TrimSpace(strings.TrimPrefix(filter, operator))
+		}
+	}
+	return "=", filter
+}
@@ -0,0 +1,194 @@
+package Audit
+
+import (
+	"encoding/csv"
+	"fmt"
+	"it_inventar/models/Storage"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LogFileName is the name of the audit log, it lives next to the data files it records
+const LogFileName = "inventar.audit.csv"
+
+// Entities whose changes are recorded
+const (
+	EntityItem      = "item"
+	EntityCategory  = "category"
+	EntitySupplier  = "supplier"
+	EntityAttribute = "attribute"
+	EntityEmployee  = "employee"
+	EntityLocation  = "location"
+	EntityFile      = "file"
+)
+
+// Operations recorded in the log
+const (
+	OpAdd     = "add"
+	OpUpdate  = "update"
+	OpRename  = "rename"
+	OpDelete  = "delete"
+	OpRestore = "restore"
+	OpPurge   = "purge"
+)
+
+// logColumns are the columns of the log file, in the order they are written
+var logColumns = []string{"Time", "User", "Operation", "Entity", "Key", "Field", "Before", "After"}
+
+// Entry is one changed field of one record. A change of several fields is written as several entries
+// with the same time, user and operation.
+type Entry struct {
+	Time      time.Time
+	User      string
+	Operation string
+	Entity    string
+	// Key identifies the record, the ID of an item or the name of a category or supplier
+	Key    string
+	Field  string
+	Before string
+	After  string
+}
+
+// Filter selects entries of the log, empty fields match everything
+type Filter struct {
+	Entity string
+	Key    string
+	User   string
+	// From and To limit the time of the entries, zero values don't limit it
+	From time.Time
+	To   time.Time
+}
+
+// Matches reports whether the entry passes the filter, the key and the user are compared case-insensitively
+func (f Filter) Matches(entry Entry) bool {
+	if f.Entity != "" && f.Entity != entry.Entity {
+		return false
+	}
+	if f.Key != "" && !strings.EqualFold(f.Key, entry.Key) {
+		return false
+	}
+	if f.User != "" && !strings.EqualFold(f.User, entry.User) {
+		return false
+	}
+	if !f.From.IsZero() && entry.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && entry.Time.After(f.To) {
+		return false
+	}
+	return true
+}
+
+// LogPath returns the path of the audit log that belongs to the passed data file
+func LogPath(filePath string) string {
+	return filepath.Join(filepath.Dir(filePath), LogFileName)
+}
+
+// CurrentUser returns the name of the user running the program
+func CurrentUser() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	for _, name := range []string{"USER", "USERNAME"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return "unknown"
+}
+
+// Record appends the entries to the audit log of the passed data file. Missing times and users are
+// filled in with the current ones. The log is only ever appended to, existing entries are never changed.
+func Record(filePath string, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	logPath := LogPath(filePath)
+	_, statErr := os.Stat(logPath)
+	file, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Comma = ';'
+	if os.IsNotExist(statErr) {
+		if err := writer.Write(logColumns); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	currentUser := CurrentUser()
+	for _, entry := range entries {
+		if entry.Time.IsZero() {
+			entry.Time = now
+		}
+		if entry.User == "" {
+			entry.User = currentUser
+		}
+		record := []string{entry.Time.Format(time.RFC3339), entry.User, entry.Operation, entry.Entity, entry.Key, entry.Field, entry.Before, entry.After}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// ReadLog reads the entries of the audit log that pass the filter, oldest first. A missing log has no entries.
+func ReadLog(filePath string, filter Filter) ([]Entry, error) {
+	file, err := os.Open(LogPath(filePath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading audit log: %v", err)
+	}
+
+	var entries []Entry
+	for index, record := range records {
+		if index == 0 || len(record) != len(logColumns) {
+			continue // Header row or a line that was cut off by a crash
+		}
+		entryTime, err := time.Parse(time.RFC3339, record[0])
+		if err != nil {
+			continue
+		}
+		entry := Entry{
+			Time:      entryTime,
+			User:      record[1],
+			Operation: record[2],
+			Entity:    record[3],
+			Key:       record[4],
+			Field:     record[5],
+			Before:    record[6],
+			After:     record[7],
+		}
+		if filter.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
@@ -3,12 +3,41 @@ package Category
 import (
 	"encoding/csv"
 	"fmt"
+	"io"
+	"it_inventar/models"
+	"it_inventar/models/Attribute"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Storage"
+	"it_inventar/models/Validation"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 )
 
-// ReadCategories reads all categories from the CSV file and returns them as a slice of strings
-func ReadCategories(filePath string) ([]string, error) {
+// DefaultCategories is a typical set of IT categories offered when the inventory is set up for the first time
+var DefaultCategories = []string{
+	"Computer/PCs",
+	"Laptops",
+	"Server",
+	"Monitoren",
+	"Drucker",
+	"Scanner",
+	"Router",
+	"Switches",
+	"Access Point",
+	"Festplatten",
+	"Kabel",
+	"Headsets und Mikrofone",
+	"Peripheriegeräte",
+}
+
+// ReadCategoryRecords reads all categories with their parents from the CSV file. The parent is the optional
+// second column, so older files with one name per row are read as categories on the top level.
+// The optional third to fifth columns are the prefix, the width and the check digit of the article numbers,
+// the optional sixth and seventh columns the default minimum and reorder quantity of the items.
+func ReadCategoryRecords(filePath string) ([]models.Category, error) {
 	// Open the CSV file
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -30,89 +59,435 @@ func ReadCategories(filePath string) ([]string, error) {
 		return nil, err
 	}
 
-	var categories []string
+	var categories []models.Category
 	// Iterate over each record (row) in the CSV file
 	for _, record := range records {
-		if len(record) > 0 {
-			categories = append(categories, strings.TrimSpace(record[0]))
+		if len(record) == 0 {
+			continue
+		}
+		category := models.Category{CategoryName: strings.TrimSpace(record[0])}
+		if len(record) > 1 {
+			category.Parent = strings.TrimSpace(record[1])
+		}
+		if len(record) > 2 {
+			category.Numbers.Prefix = strings.TrimSpace(record[2])
 		}
+		if len(record) > 3 {
+			category.Numbers.Width = models.StringToInt(record[3])
+		}
+		if len(record) > 4 {
+			category.Numbers.CheckDigit = strings.TrimSpace(record[4]) == "true"
+		}
+		if len(record) > 6 {
+			category.StockLevel = models.ParseStockLevel(record[5], record[6])
+		}
+		categories = append(categories, category)
 	}
 	return categories, nil
 }
 
-// AddCategoryToFile adds a new category to the CSV file
+// ReadCategories reads all categories from the CSV file and returns their names as a slice of strings
+func ReadCategories(filePath string) ([]string, error) {
+	categories, err := ReadCategoryRecords(filePath)
+	return Names(categories), err
+}
+
+// Names returns the names of the categories
+func Names(categories []models.Category) []string {
+	names := make([]string, len(categories))
+	for index, category := range categories {
+		names[index] = category.CategoryName
+	}
+	return names
+}
+
+// AddCategoryToFile adds a new category on the top level to the CSV file
 func AddCategoryToFile(filePath, categoryName string) error {
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	return AddSubcategory(filePath, categoryName, "")
+}
+
+// AddSubcategory adds a new category below the parent to the CSV file, an empty parent adds it on the top level
+func AddSubcategory(filePath, categoryName, parent string) error {
+	// Lock the file so a category added by another user in the meantime is not lost
+	lock, err := Storage.Lock(filePath)
 	if err != nil {
 		return err
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			fmt.Println("Error closing file:", err)
-		}
-	}(file)
+	defer lock.Unlock()
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	categories, err := ReadCategoryRecords(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if duplicate, found := Validation.FindDuplicate(categoryName, Names(categories)); found {
+		return fmt.Errorf("category '%s' already exists", duplicate)
+	}
+	if parent != "" && !slices.Contains(Names(categories), parent) {
+		return fmt.Errorf("category '%s' no longer exists, it was probably changed by another user", parent)
+	}
 
-	if err := writer.Write([]string{categoryName}); err != nil {
+	if err := OverwriteCategoryFile(filePath, append(categories, models.Category{CategoryName: categoryName, Parent: parent})); err != nil {
+		return err
+	}
+	entries := []Audit.Entry{{Operation: Audit.OpAdd, Entity: Audit.EntityCategory, Key: categoryName, Field: "Name", After: categoryName}}
+	if parent != "" {
+		entries = append(entries, Audit.Entry{Operation: Audit.OpAdd, Entity: Audit.EntityCategory, Key: categoryName, Field: "Parent", After: parent})
+	}
+	return Audit.Record(filePath, entries)
+}
+
+// DeleteCategory removes the category with the passed name from the existing list.
+// Its subcategories move up to the parent of the deleted category. The optional update is applied to the active
+// items of the category, e.g. to reassign them, and saved in one transaction with the category file.
+func DeleteCategory(filePath, categoryName string, items models.ItemStore, update func(item *models.Item)) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
 		return err
 	}
+	defer lock.Unlock()
 
-	return nil
+	// Read the current list, it may have been changed by another user since it was displayed
+	categories, err := ReadCategoryRecords(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading categories: %v", err)
+	}
+
+	for index, category := range categories {
+		if category.CategoryName == categoryName {
+			// Remove the selected category
+			categories = append(categories[:index], categories[index+1:]...)
+
+			entries := []Audit.Entry{{Operation: Audit.OpDelete, Entity: Audit.EntityCategory, Key: categoryName, Field: "Name", Before: categoryName}}
+			for child := range categories {
+				if categories[child].Parent == categoryName {
+					categories[child].Parent = category.Parent
+					entries = append(entries, Audit.Entry{Operation: Audit.OpUpdate, Entity: Audit.EntityCategory, Key: categories[child].CategoryName, Field: "Parent", Before: categoryName, After: category.Parent})
+				}
+			}
+
+			if update == nil {
+				// Overwrite the category file
+				if err := OverwriteCategoryFile(filePath, categories); err != nil {
+					return err
+				}
+				return Audit.Record(filePath, entries)
+			}
+			var ids []int
+			for _, item := range models.ItemsReferencing(items.GetAllItems(), Audit.EntityCategory, categoryName) {
+				ids = append(ids, item.ID)
+			}
+			err := items.UpdateItems(ids, update, Storage.FileWrite{Path: filePath, Write: func(w io.Writer) error {
+				return writeCategories(w, categories)
+			}})
+			if err != nil {
+				return err
+			}
+			return Audit.Record(filePath, entries)
+		}
+	}
+	return fmt.Errorf("category '%s' no longer exists, it was probably changed by another user", categoryName)
 }
 
-// IsValidCategoryName checks if the category name meets the validation criteria
-func IsValidCategoryName(name string) bool {
-	if name == "" {
-		return false
+// MoveCategory puts the category below a new parent, an empty parent moves it to the top level.
+// A category can't be moved below itself or one of its own subcategories.
+func MoveCategory(filePath, categoryName, parent string) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
 	}
-	for _, char := range name {
-		if !(char >= 'a' && char <= 'z') && !(char >= 'A' && char <= 'Z') && !(char >= '0' && char <= '9') && char != ' ' {
-			return false
+	defer lock.Unlock()
+
+	categories, err := ReadCategoryRecords(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading categories: %v", err)
+	}
+	index := slices.IndexFunc(categories, func(category models.Category) bool { return category.CategoryName == categoryName })
+	if index < 0 {
+		return fmt.Errorf("category '%s' no longer exists, it was probably changed by another user", categoryName)
+	}
+	if parent != "" {
+		if !slices.Contains(Names(categories), parent) {
+			return fmt.Errorf("category '%s' no longer exists, it was probably changed by another user", parent)
 		}
+		if slices.Contains(Descendants(Tree(categories), categoryName), parent) {
+			return fmt.Errorf("category '%s' can't be moved below itself or one of its subcategories", categoryName)
+		}
+	}
+
+	before := categories[index].Parent
+	if before == parent {
+		return nil
+	}
+	categories[index].Parent = parent
+	if err := OverwriteCategoryFile(filePath, categories); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, []Audit.Entry{{Operation: Audit.OpUpdate, Entity: Audit.EntityCategory, Key: categoryName, Field: "Parent", Before: before, After: parent}})
+}
+
+// SetNumberPattern sets the pattern of the article numbers of the category, an empty pattern removes it
+func SetNumberPattern(filePath, categoryName string, pattern models.NumberPattern) error {
+	if err := pattern.Validate(); err != nil {
+		return err
+	}
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
 	}
-	return true
+	defer lock.Unlock()
+
+	categories, err := ReadCategoryRecords(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading categories: %v", err)
+	}
+	index := slices.Index(Names(categories), categoryName)
+	if index < 0 {
+		return fmt.Errorf("category '%s' no longer exists, it was probably changed by another user", categoryName)
+	}
+	before := categories[index].Numbers
+	if before == pattern {
+		return nil
+	}
+	categories[index].Numbers = pattern
+	if err := OverwriteCategoryFile(filePath, categories); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, []Audit.Entry{{Operation: Audit.OpUpdate, Entity: Audit.EntityCategory, Key: categoryName, Field: "Numbers", Before: before.String(), After: pattern.String()}})
 }
 
-// DeleteCategory from existing list
-func DeleteCategory(filePath string, index int) error {
-	categories, err := ReadCategories(filePath)
+// SetStockLevel sets the default minimum and reorder quantity of the items of the category,
+// an undefined level removes it, so the category uses the one of its parent
+func SetStockLevel(filePath, categoryName string, level models.StockLevel) error {
+	if err := level.Validate(); err != nil {
+		return err
+	}
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	categories, err := ReadCategoryRecords(filePath)
 	if err != nil {
 		return fmt.Errorf("error reading categories: %v", err)
 	}
+	index := slices.Index(Names(categories), categoryName)
+	if index < 0 {
+		return fmt.Errorf("category '%s' no longer exists, it was probably changed by another user", categoryName)
+	}
+	before := categories[index].StockLevel
+	if before == level {
+		return nil
+	}
+	categories[index].StockLevel = level
+	if err := OverwriteCategoryFile(filePath, categories); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, []Audit.Entry{{Operation: Audit.OpUpdate, Entity: Audit.EntityCategory, Key: categoryName, Field: "StockLevel", Before: before.String(), After: level.String()}})
+}
 
-	// Remove the selected supplier
-	categories = append(categories[:index], categories[index+1:]...)
+// StockLevelFor returns the stock level of the category, or of its closest parent that has one,
+// together with the name of the category it is defined in. An own minimum of 0 stops the inheritance.
+func StockLevelFor(categories []models.Category, name string) (models.StockLevel, string) {
+	path := Ancestors(Tree(categories), name)
+	for index := len(path) - 1; index >= 0; index-- {
+		position := slices.Index(Names(categories), path[index])
+		if position >= 0 && categories[position].StockLevel.IsDefined() {
+			return categories[position].StockLevel, path[index]
+		}
+	}
+	return models.StockLevel{}, ""
+}
 
-	// Overwrite the supplier file
-	return OverwriteCategoryFile(filePath, categories)
+// ItemStockLevel returns the stock level of the item, items without a level of their own use the one of their category
+func ItemStockLevel(categories []models.Category, item models.Item) models.StockLevel {
+	if item.StockLevel.IsDefined() {
+		return item.StockLevel
+	}
+	level, _ := StockLevelFor(categories, item.Category)
+	return level
 }
 
-// OverwriteCategoryFile overwrites the content of the given file with the provided list of categories.
-func OverwriteCategoryFile(filePath string, categories []string) error {
-	file, err := os.Create(filePath)
+// NumberPatternFor returns the number pattern of the category, or of its closest parent that has one,
+// together with the name of the category it is defined in
+func NumberPatternFor(categories []models.Category, name string) (models.NumberPattern, string) {
+	path := Ancestors(Tree(categories), name)
+	for index := len(path) - 1; index >= 0; index-- {
+		position := slices.Index(Names(categories), path[index])
+		if position >= 0 && categories[position].Numbers.IsSet() {
+			return categories[position].Numbers, path[index]
+		}
+	}
+	return models.NumberPattern{}, ""
+}
+
+// SetCategories replaces the list of categories, names that were added or removed, changed parents,
+// number patterns and stock levels are recorded in the audit log
+func SetCategories(filePath string, categories []models.Category) error {
+	lock, err := Storage.Lock(filePath)
 	if err != nil {
 		return err
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
+	defer lock.Unlock()
 
+	_, entries, err := SetCategoriesWrite(filePath, categories)
+	if err != nil {
+		return err
+	}
+	if err := OverwriteCategoryFile(filePath, categories); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, entries)
+}
+
+// SetCategoriesWrite returns the write that replaces the list of categories and the audit entries of the change, so
+// the list can be saved in the same transaction as the items. The caller holds the lock of the category file.
+func SetCategoriesWrite(filePath string, categories []models.Category) (Storage.FileWrite, []Audit.Entry, error) {
+	current, err := ReadCategoryRecords(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return Storage.FileWrite{}, nil, err
+	}
+	write := Storage.FileWrite{Path: filePath, Write: func(w io.Writer) error {
+		return writeCategories(w, categories)
+	}}
+
+	var entries []Audit.Entry
+	for _, category := range current {
+		if !slices.Contains(Names(categories), category.CategoryName) {
+			entries = append(entries, Audit.Entry{Operation: Audit.OpDelete, Entity: Audit.EntityCategory, Key: category.CategoryName, Field: "Name", Before: category.CategoryName})
+		}
+	}
+	for _, category := range categories {
+		index := slices.IndexFunc(current, func(old models.Category) bool { return old.CategoryName == category.CategoryName })
+		if index < 0 {
+			entries = append(entries, Audit.Entry{Operation: Audit.OpAdd, Entity: Audit.EntityCategory, Key: category.CategoryName, Field: "Name", After: category.CategoryName})
+			if category.Parent != "" {
+				entries = append(entries, Audit.Entry{Operation: Audit.OpAdd, Entity: Audit.EntityCategory, Key: category.CategoryName, Field: "Parent", After: category.Parent})
+			}
+			continue
+		}
+		if current[index].Parent != category.Parent {
+			entries = append(entries, Audit.Entry{Operation: Audit.OpUpdate, Entity: Audit.EntityCategory, Key: category.CategoryName, Field: "Parent", Before: current[index].Parent, After: category.Parent})
+		}
+		if current[index].Numbers != category.Numbers {
+			entries = append(entries, Audit.Entry{Operation: Audit.OpUpdate, Entity: Audit.EntityCategory, Key: category.CategoryName, Field: "Numbers", Before: current[index].Numbers.String(), After: category.Numbers.String()})
+		}
+		if current[index].StockLevel != category.StockLevel {
+			entries = append(entries, Audit.Entry{Operation: Audit.OpUpdate, Entity: Audit.EntityCategory, Key: category.CategoryName, Field: "StockLevel", Before: current[index].StockLevel.String(), After: category.StockLevel.String()})
 		}
-	}(file)
+	}
+	return write, entries, nil
+}
+
+// OverwriteCategoryFile overwrites the content of the given file with the provided list of categories.
+// The file is replaced atomically, so a crash while saving never leaves a half written file behind.
+func OverwriteCategoryFile(filePath string, categories []models.Category) error {
+	// Keep the previous version, so a wrong change can be restored from the service menu
+	if err := Storage.TakeSnapshot(filePath); err != nil {
+		return err
+	}
+
+	return Storage.WriteFileAtomic(filePath, func(w io.Writer) error {
+		return writeCategories(w, categories)
+	})
+}
 
+// writeCategories writes the categories in the format of the category file.
+// The parent, the number pattern and the stock level are only written when needed, so a list without them keeps the
+// old format.
+func writeCategories(w io.Writer, categories []models.Category) error {
 	// Initialize a CSV writer to write to the file
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	writer := csv.NewWriter(w)
 
 	// Write each category as a new row in the CSV file
-	for _, categories := range categories {
-		err = writer.Write([]string{categories})
-		if err != nil {
+	for _, category := range categories {
+		record := []string{category.CategoryName}
+		if category.Parent != "" || category.Numbers.IsSet() || category.StockLevel.IsDefined() {
+			record = append(record, category.Parent)
+		}
+		if category.Numbers.IsSet() || category.StockLevel.IsDefined() {
+			record = append(record, category.Numbers.Prefix, models.IntToString(category.Numbers.Width), strconv.FormatBool(category.Numbers.CheckDigit))
+		}
+		if category.StockLevel.IsDefined() {
+			record = append(record, models.IntToString(category.StockLevel.Minimum), models.IntToString(category.StockLevel.Reorder))
+		}
+		if err := writer.Write(record); err != nil {
 			return err
 		}
 	}
-	return nil
+	writer.Flush()
+	return writer.Error()
+}
+
+// RenameCategory renames the category in the list and in every item that uses it.
+// The category file, the attribute file and the data file of the items are replaced in one transaction.
+func RenameCategory(filePath, oldName, newName string, items models.ItemStore, attributesPath string) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	attributesLock, err := Storage.Lock(attributesPath)
+	if err != nil {
+		return err
+	}
+	defer attributesLock.Unlock()
+
+	categories, err := ReadCategoryRecords(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading categories: %v", err)
+	}
+	// Only the case of the name may change, other categories must not have the new name
+	others := slices.DeleteFunc(Names(categories), func(name string) bool { return name == oldName })
+	if duplicate, found := Validation.FindDuplicate(newName, others); found {
+		return fmt.Errorf("category '%s' already exists", duplicate)
+	}
+	index := slices.Index(Names(categories), oldName)
+	if index < 0 {
+		return fmt.Errorf("category '%s' no longer exists, it was probably changed by another user", oldName)
+	}
+	categories[index].CategoryName = newName
+	// The subcategories keep their place below the renamed category
+	for child := range categories {
+		if categories[child].Parent == oldName {
+			categories[child].Parent = newName
+		}
+	}
+
+	// The attributes of the category move along with it
+	attributeWrites, err := Attribute.RenameCategoryWrites(attributesPath, oldName, newName)
+	if err != nil {
+		return err
+	}
+
+	var ids []int
+	for _, item := range models.AllItemsReferencing(items.GetAllItems(), Audit.EntityCategory, oldName) {
+		ids = append(ids, item.ID)
+	}
+	files := append([]Storage.FileWrite{{Path: filePath, Write: func(w io.Writer) error {
+		return writeCategories(w, categories)
+	}}}, attributeWrites...)
+	err = items.UpdateItems(ids, func(item *models.Item) {
+		item.SetReference(Audit.EntityCategory, newName)
+	}, files...)
+	if err != nil {
+		return err
+	}
+	return Audit.Record(filePath, []Audit.Entry{{Operation: Audit.OpRename, Entity: Audit.EntityCategory, Key: oldName, Field: "Name", Before: oldName, After: newName}})
+}
+
+// CreateCategoryFile creates a new category file with the passed categories on the top level,
+// an existing file is never overwritten
+func CreateCategoryFile(filePath string, categories []string) error {
+	if _, err := os.Stat(filePath); err == nil {
+		return fmt.Errorf("%s already exists", filePath)
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	records := make([]models.Category, len(categories))
+	for index, name := range categories {
+		records[index] = models.Category{CategoryName: name}
+	}
+	return OverwriteCategoryFile(filePath, records)
 }
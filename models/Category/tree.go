@@ -0,0 +1,115 @@
+package Category
+
+import (
+	"it_inventar/models"
+	"slices"
+	"strings"
+)
+
+// PathSeparator separates the names of a category and its parents in a path, e.g. "Peripherals > Monitors > Gaming"
+const PathSeparator = " > "
+
+// Node is a category at its place in the tree. Parent is the category it is shown under, which is empty for
+// categories whose parent no longer exists or that are part of a cycle in a hand-edited file.
+type Node struct {
+	CategoryName string
+	Parent       string
+	Depth        int
+}
+
+// Tree returns the categories in tree order: every category is followed by its subcategories,
+// categories with the same parent keep the order of the category file
+func Tree(categories []models.Category) []Node {
+	names := Names(categories)
+	var nodes []Node
+	visited := map[string]bool{}
+
+	var walk func(parent string, depth int)
+	walk = func(parent string, depth int) {
+		for _, category := range categories {
+			if category.Parent != parent || visited[category.CategoryName] {
+				continue
+			}
+			visited[category.CategoryName] = true
+			nodes = append(nodes, Node{CategoryName: category.CategoryName, Parent: parent, Depth: depth})
+			walk(category.CategoryName, depth+1)
+		}
+	}
+	addRoot := func(category models.Category) {
+		visited[category.CategoryName] = true
+		nodes = append(nodes, Node{CategoryName: category.CategoryName})
+		walk(category.CategoryName, 1)
+	}
+
+	for _, category := range categories {
+		if !visited[category.CategoryName] && (category.Parent == "" || !slices.Contains(names, category.Parent)) {
+			addRoot(category)
+		}
+	}
+	// What is left is part of a cycle, it is shown on the top level so it can still be chosen and moved
+	for _, category := range categories {
+		if !visited[category.CategoryName] {
+			addRoot(category)
+		}
+	}
+	return nodes
+}
+
+// NodeNames returns the names of the nodes in tree order
+func NodeNames(nodes []Node) []string {
+	names := make([]string, len(nodes))
+	for index, node := range nodes {
+		names[index] = node.CategoryName
+	}
+	return names
+}
+
+// Children returns the direct subcategories of the parent, an empty parent returns the top level
+func Children(nodes []Node, parent string) []Node {
+	var children []Node
+	for _, node := range nodes {
+		if node.Parent == parent {
+			children = append(children, node)
+		}
+	}
+	return children
+}
+
+// HasChildren reports whether the category has subcategories
+func HasChildren(nodes []Node, name string) bool {
+	return len(Children(nodes, name)) > 0
+}
+
+// Descendants returns the category and all of its subcategories, at any depth
+func Descendants(nodes []Node, name string) []string {
+	index := slices.IndexFunc(nodes, func(node Node) bool { return node.CategoryName == name })
+	if index < 0 {
+		return nil
+	}
+	descendants := []string{name}
+	for _, node := range nodes[index+1:] {
+		if node.Depth <= nodes[index].Depth {
+			break
+		}
+		descendants = append(descendants, node.CategoryName)
+	}
+	return descendants
+}
+
+// Ancestors returns the names from the top level down to the category itself
+func Ancestors(nodes []Node, name string) []string {
+	path := []string{name}
+	for {
+		index := slices.IndexFunc(nodes, func(node Node) bool { return node.CategoryName == name })
+		if index < 0 || nodes[index].Parent == "" {
+			return path
+		}
+		name = nodes[index].Parent
+		path = append([]string{name}, path...)
+	}
+}
+
+// Path returns the names from the top level down to the category, joined with PathSeparator
+func Path(nodes []Node, name string) string {
+	return strings.Join(Ancestors(nodes, name), PathSeparator)
+}
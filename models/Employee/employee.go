@@ -0,0 +1,213 @@
+package Employee
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"it_inventar/models"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Storage"
+	"it_inventar/models/Validation"
+	"os"
+	"slices"
+	"strings"
+)
+
+// columns are the columns of the employee file, in the order they are written
+var columns = []string{"Name", "Department", "Email"}
+
+// ReadEmployees reads all employees from the CSV file, a missing file has no employees
+func ReadEmployees(filePath string) ([]models.Employee, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := file.Close()
+		if closeErr != nil {
+			fmt.Printf("Error closing file: %v\n", closeErr)
+		}
+	}()
+
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var employees []models.Employee
+	for index, record := range records {
+		if index == 0 || len(record) < len(columns) {
+			continue // Header row or an incomplete line
+		}
+		employees = append(employees, models.Employee{
+			EmployeeName: strings.TrimSpace(record[0]),
+			Department:   strings.TrimSpace(record[1]),
+			Email:        strings.TrimSpace(record[2]),
+		})
+	}
+	return employees, nil
+}
+
+// Names returns the names of the employees in the order of the file
+func Names(employees []models.Employee) []string {
+	names := make([]string, 0, len(employees))
+	for _, employee := range employees {
+		names = append(names, employee.EmployeeName)
+	}
+	return names
+}
+
+// AddEmployee adds a new employee to the CSV file, the name must not be used yet
+func AddEmployee(filePath string, employee models.Employee) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	employees, err := ReadEmployees(filePath)
+	if err != nil {
+		return err
+	}
+	if duplicate, found := Validation.FindDuplicate(employee.EmployeeName, Names(employees)); found {
+		return fmt.Errorf("employee '%s' already exists", duplicate)
+	}
+	if err := overwriteEmployeeFile(filePath, append(employees, employee)); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, fieldChanges(Audit.OpAdd, nil, &employee))
+}
+
+// DeleteEmployee removes the employee with the passed name from the register
+func DeleteEmployee(filePath, employeeName string) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	employees, err := ReadEmployees(filePath)
+	if err != nil {
+		return err
+	}
+	position := slices.IndexFunc(employees, func(employee models.Employee) bool { return employee.EmployeeName == employeeName })
+	if position < 0 {
+		return fmt.Errorf("employee '%s' no longer exists, it was probably changed by another user", employeeName)
+	}
+	deleted := employees[position]
+	if err := overwriteEmployeeFile(filePath, slices.Delete(employees, position, position+1)); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, fieldChanges(Audit.OpDelete, &deleted, nil))
+}
+
+// SetEmployees replaces the register, every added, removed or changed employee is recorded in the audit log
+func SetEmployees(filePath string, employees []models.Employee) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	_, entries, err := SetEmployeesWrite(filePath, employees)
+	if err != nil {
+		return err
+	}
+	if err := overwriteEmployeeFile(filePath, employees); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, entries)
+}
+
+// SetEmployeesWrite returns the write that replaces the register and the audit entries of the change, so the
+// register can be saved in the same transaction as the items. The caller holds the lock of the employee file.
+func SetEmployeesWrite(filePath string, employees []models.Employee) (Storage.FileWrite, []Audit.Entry, error) {
+	current, err := ReadEmployees(filePath)
+	if err != nil {
+		return Storage.FileWrite{}, nil, err
+	}
+	write := Storage.FileWrite{Path: filePath, Write: func(w io.Writer) error {
+		return writeEmployees(w, employees)
+	}}
+
+	var entries []Audit.Entry
+	for _, previous := range current {
+		if position := slices.IndexFunc(employees, func(employee models.Employee) bool { return employee.EmployeeName == previous.EmployeeName }); position >= 0 {
+			entries = append(entries, fieldChanges(Audit.OpUpdate, &previous, &employees[position])...)
+		} else {
+			entries = append(entries, fieldChanges(Audit.OpDelete, &previous, nil)...)
+		}
+	}
+	for _, employee := range employees {
+		if !slices.ContainsFunc(current, func(previous models.Employee) bool { return previous.EmployeeName == employee.EmployeeName }) {
+			entries = append(entries, fieldChanges(Audit.OpAdd, nil, &employee)...)
+		}
+	}
+	return write, entries, nil
+}
+
+// overwriteEmployeeFile replaces the employee file atomically after keeping a snapshot of it
+func overwriteEmployeeFile(filePath string, employees []models.Employee) error {
+	if err := Storage.TakeSnapshot(filePath); err != nil {
+		return err
+	}
+	return Storage.WriteFileAtomic(filePath, func(w io.Writer) error {
+		return writeEmployees(w, employees)
+	})
+}
+
+// writeEmployees writes the header row and one employee per row
+func writeEmployees(w io.Writer, employees []models.Employee) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, employee := range employees {
+		if err := writer.Write(employeeFields(employee)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// employeeFields returns the values of the employee in the order of columns
+func employeeFields(employee models.Employee) []string {
+	return []string{employee.EmployeeName, employee.Department, employee.Email}
+}
+
+// fieldChanges returns an audit entry for every field that differs, nil stands for an employee that did not exist
+func fieldChanges(operation string, before, after *models.Employee) []Audit.Entry {
+	var oldFields, newFields []string
+	key := ""
+	if before != nil {
+		oldFields = employeeFields(*before)
+		key = before.EmployeeName
+	}
+	if after != nil {
+		newFields = employeeFields(*after)
+		key = after.EmployeeName
+	}
+
+	var entries []Audit.Entry
+	for index, column := range columns {
+		var oldValue, newValue string
+		if oldFields != nil {
+			oldValue = oldFields[index]
+		}
+		if newFields != nil {
+			newValue = newFields[index]
+		}
+		if oldValue != newValue {
+			entries = append(entries, Audit.Entry{Operation: operation, Entity: Audit.EntityEmployee, Key: key, Field: column, Before: oldValue, After: newValue})
+		}
+	}
+	return entries
+}
@@ -0,0 +1,172 @@
+package Ledger
+
+import (
+	"encoding/csv"
+	"fmt"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Storage"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Reasons of a movement
+const (
+	ReasonPurchase   = "purchase"
+	ReasonIssue      = "issue"
+	ReasonReturn     = "return"
+	ReasonCorrection = "correction"
+)
+
+// Reasons are all reasons of a movement in the order they are offered
+var Reasons = []string{ReasonPurchase, ReasonIssue, ReasonReturn, ReasonCorrection}
+
+// InReasons are the reasons pieces come into stock for, OutReasons the ones they leave it for
+var (
+	InReasons  = []string{ReasonPurchase, ReasonReturn, ReasonCorrection}
+	OutReasons = []string{ReasonIssue, ReasonCorrection}
+)
+
+// columns are the columns of the ledger file, in the order they are written
+var columns = []string{"Time", "User", "ItemID", "Amount", "Reason", "Location", "Reference", "Comment"}
+
+// Movement is one booking of an item, a positive amount comes into stock and a negative one leaves it
+type Movement struct {
+	Time   time.Time
+	User   string
+	ItemID int
+	Amount int
+	Reason string
+	// Location is where the pieces came in or left, empty when they have no location
+	Location  string
+	Reference string
+	Comment   string
+}
+
+// Validate checks that the reason is known and fits the direction of the amount
+func (m Movement) Validate() error {
+	if m.Amount == 0 {
+		return fmt.Errorf("a movement needs an amount")
+	}
+	if !slices.Contains(Reasons, m.Reason) {
+		return fmt.Errorf("unknown reason %q", m.Reason)
+	}
+	if m.Amount > 0 && !slices.Contains(InReasons, m.Reason) {
+		return fmt.Errorf("pieces can't come into stock for %s", m.Reason)
+	}
+	if m.Amount < 0 && !slices.Contains(OutReasons, m.Reason) {
+		return fmt.Errorf("pieces can't leave the stock for %s", m.Reason)
+	}
+	return nil
+}
+
+// Balance returns the stock the movements add up to
+func Balance(movements []Movement) int {
+	balance := 0
+	for _, movement := range movements {
+		balance += movement.Amount
+	}
+	return balance
+}
+
+// Record appends the movements to the ledger. Missing times and users are filled in with the current ones.
+// Like the audit log, the ledger is only ever appended to, existing movements are never changed.
+func Record(filePath string, movements []Movement) error {
+	if len(movements) == 0 {
+		return nil
+	}
+	for _, movement := range movements {
+		if err := movement.Validate(); err != nil {
+			return err
+		}
+	}
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	_, statErr := os.Stat(filePath)
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Comma = ';'
+	if os.IsNotExist(statErr) {
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	currentUser := Audit.CurrentUser()
+	for _, movement := range movements {
+		if movement.Time.IsZero() {
+			movement.Time = now
+		}
+		if movement.User == "" {
+			movement.User = currentUser
+		}
+		record := []string{movement.Time.Format(time.RFC3339), movement.User, strconv.Itoa(movement.ItemID), strconv.Itoa(movement.Amount),
+			movement.Reason, movement.Location, movement.Reference, movement.Comment}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// ReadMovements reads the movements of the item with the passed ID, oldest first. An ID of 0 reads the movements
+// of all items. A missing ledger has no movements.
+func ReadMovements(filePath string, itemID int) ([]Movement, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading the movements: %v", err)
+	}
+
+	var movements []Movement
+	for index, record := range records {
+		if index == 0 || len(record) != len(columns) {
+			continue // Header row or a line that was cut off by a crash
+		}
+		movementTime, timeErr := time.Parse(time.RFC3339, record[0])
+		id, idErr := strconv.Atoi(record[2])
+		amount, amountErr := strconv.Atoi(record[3])
+		if timeErr != nil || idErr != nil || amountErr != nil {
+			continue
+		}
+		if itemID != 0 && id != itemID {
+			continue
+		}
+		movements = append(movements, Movement{
+			Time:      movementTime,
+			User:      record[1],
+			ItemID:    id,
+			Amount:    amount,
+			Reason:    record[4],
+			Location:  record[5],
+			Reference: record[6],
+			Comment:   record[7],
+		})
+	}
+	return movements, nil
+}
@@ -0,0 +1,202 @@
+package Location
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"it_inventar/models"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Storage"
+	"it_inventar/models/Validation"
+	"os"
+	"slices"
+	"strings"
+)
+
+// header is the only column of the location file
+const header = "Name"
+
+// ReadLocations reads the names of all locations from the CSV file, a missing file has no locations
+func ReadLocations(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := file.Close()
+		if closeErr != nil {
+			fmt.Printf("Error closing file: %v\n", closeErr)
+		}
+	}()
+
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var locations []string
+	for index, record := range records {
+		if index == 0 || len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue // Header row or an empty line
+		}
+		locations = append(locations, strings.TrimSpace(record[0]))
+	}
+	return locations, nil
+}
+
+// AddLocation adds a new location to the CSV file, the name must not be used yet
+func AddLocation(filePath, locationName string) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	locations, err := ReadLocations(filePath)
+	if err != nil {
+		return err
+	}
+	if duplicate, found := Validation.FindDuplicate(locationName, locations); found {
+		return fmt.Errorf("location '%s' already exists", duplicate)
+	}
+	if err := overwriteLocationFile(filePath, append(locations, locationName)); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, []Audit.Entry{{Operation: Audit.OpAdd, Entity: Audit.EntityLocation, Key: locationName, Field: "Name", After: locationName}})
+}
+
+// DeleteLocation removes the location with the passed name from the list
+func DeleteLocation(filePath, locationName string) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	locations, err := ReadLocations(filePath)
+	if err != nil {
+		return err
+	}
+	position := slices.Index(locations, locationName)
+	if position < 0 {
+		return fmt.Errorf("location '%s' no longer exists, it was probably changed by another user", locationName)
+	}
+	if err := overwriteLocationFile(filePath, slices.Delete(locations, position, position+1)); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, []Audit.Entry{{Operation: Audit.OpDelete, Entity: Audit.EntityLocation, Key: locationName, Field: "Name", Before: locationName}})
+}
+
+// RenameLocation renames the location in the list and in every item that has pieces there.
+// The location file and the data file of the items are replaced in one transaction.
+func RenameLocation(filePath, oldName, newName string, items models.ItemStore) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	locations, err := ReadLocations(filePath)
+	if err != nil {
+		return err
+	}
+	// Only the case of the name may change, other locations must not have the new name
+	others := slices.DeleteFunc(slices.Clone(locations), func(name string) bool { return name == oldName })
+	if duplicate, found := Validation.FindDuplicate(newName, others); found {
+		return fmt.Errorf("location '%s' already exists", duplicate)
+	}
+	position := slices.Index(locations, oldName)
+	if position < 0 {
+		return fmt.Errorf("location '%s' no longer exists, it was probably changed by another user", oldName)
+	}
+	locations[position] = newName
+
+	var ids []int
+	for _, item := range models.ItemsAtLocation(items.GetAllItems(), oldName) {
+		ids = append(ids, item.ID)
+	}
+	err = items.UpdateItems(ids, func(item *models.Item) {
+		item.RenameLocation(oldName, newName)
+	}, Storage.FileWrite{Path: filePath, Write: func(w io.Writer) error {
+		return writeLocations(w, locations)
+	}})
+	if err != nil {
+		return err
+	}
+	return Audit.Record(filePath, []Audit.Entry{{Operation: Audit.OpRename, Entity: Audit.EntityLocation, Key: oldName, Field: "Name", Before: oldName, After: newName}})
+}
+
+// SetLocations replaces the list of locations, added and removed locations are recorded in the audit log
+func SetLocations(filePath string, locations []string) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	_, entries, err := SetLocationsWrite(filePath, locations)
+	if err != nil {
+		return err
+	}
+	if err := overwriteLocationFile(filePath, locations); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, entries)
+}
+
+// SetLocationsWrite returns the write that replaces the list of locations and the audit entries of the change, so
+// the list can be saved in the same transaction as the items. The caller holds the lock of the location file.
+func SetLocationsWrite(filePath string, locations []string) (Storage.FileWrite, []Audit.Entry, error) {
+	current, err := ReadLocations(filePath)
+	if err != nil {
+		return Storage.FileWrite{}, nil, err
+	}
+	write := Storage.FileWrite{Path: filePath, Write: func(w io.Writer) error {
+		return writeLocations(w, locations)
+	}}
+
+	var entries []Audit.Entry
+	for _, location := range current {
+		if !slices.Contains(locations, location) {
+			entries = append(entries, Audit.Entry{Operation: Audit.OpDelete, Entity: Audit.EntityLocation, Key: location, Field: "Name", Before: location})
+		}
+	}
+	for _, location := range locations {
+		if !slices.Contains(current, location) {
+			entries = append(entries, Audit.Entry{Operation: Audit.OpAdd, Entity: Audit.EntityLocation, Key: location, Field: "Name", After: location})
+		}
+	}
+	return write, entries, nil
+}
+
+// overwriteLocationFile replaces the location file atomically after keeping a snapshot of it
+func overwriteLocationFile(filePath string, locations []string) error {
+	if err := Storage.TakeSnapshot(filePath); err != nil {
+		return err
+	}
+	return Storage.WriteFileAtomic(filePath, func(w io.Writer) error {
+		return writeLocations(w, locations)
+	})
+}
+
+// writeLocations writes the header row and one location per row
+func writeLocations(w io.Writer, locations []string) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+	if err := writer.Write([]string{header}); err != nil {
+		return err
+	}
+	for _, location := range locations {
+		if err := writer.Write([]string{location}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
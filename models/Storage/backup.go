@@ -0,0 +1,279 @@
+package Storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// BackupDirName is the directory next to the data files that holds the snapshots
+	BackupDirName = "backups"
+
+	snapshotTimeFormat = "20060102-150405.000"
+	snapshotSuffix     = ".bak"
+)
+
+// BackupPolicy decides how many snapshots of every file are kept
+type BackupPolicy struct {
+	// MaxCount is the number of snapshots kept per file, 0 keeps all
+	MaxCount int
+	// MaxAge removes snapshots older than this, 0 keeps them forever
+	MaxAge time.Duration
+	// Dir is the directory of the snapshots, empty means BackupDirName next to the file
+	Dir string
+}
+
+// Backups is the policy used by TakeSnapshot
+var Backups = BackupPolicy{MaxCount: 20, MaxAge: 30 * 24 * time.Hour}
+
+// Snapshot is a copy of a data file taken before it was saved
+type Snapshot struct {
+	Path     string
+	FileName string
+	Time     time.Time
+}
+
+// DiffLine is one line of the difference between two file versions, Change is ' ', '+' or '-'
+type DiffLine struct {
+	Change byte
+	Text   string
+}
+
+// BackupDir returns the directory that holds the snapshots of the passed file
+func BackupDir(filePath string) string {
+	if Backups.Dir != "" {
+		return Backups.Dir
+	}
+	return filepath.Join(filepath.Dir(filePath), BackupDirName)
+}
+
+// TakeSnapshot copies the current content of the file into the backup directory and removes old snapshots.
+// Files that don't exist yet have nothing to back up.
+func TakeSnapshot(filePath string) error {
+	content, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	backupDir := BackupDir(filePath)
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return err
+	}
+	fileName := filepath.Base(filePath)
+	snapshotPath := filepath.Join(backupDir, fileName+"."+time.Now().Format(snapshotTimeFormat)+snapshotSuffix)
+	if err := os.WriteFile(snapshotPath, content, 0644); err != nil {
+		return err
+	}
+	return pruneSnapshots(backupDir, fileName)
+}
+
+// ListSnapshots returns all snapshots of the passed files, newest first
+func ListSnapshots(filePaths ...string) ([]Snapshot, error) {
+	var snapshots []Snapshot
+	for _, filePath := range filePaths {
+		fileSnapshots, err := listSnapshotsIn(BackupDir(filePath), filepath.Base(filePath))
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, fileSnapshots...)
+	}
+	sortNewestFirst(snapshots)
+	return snapshots, nil
+}
+
+// listSnapshotsIn returns the snapshots of one file in the backup directory
+func listSnapshotsIn(backupDir, fileName string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(backupDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		if snapshot, ok := parseSnapshotName(backupDir, fileName, entry.Name()); ok {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots, nil
+}
+
+// sortNewestFirst orders the snapshots by their time, newest first
+func sortNewestFirst(snapshots []Snapshot) {
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.After(snapshots[j].Time)
+	})
+}
+
+// RestoreSnapshot replaces the target file with the content of the snapshot.
+// The current content is snapshotted first, so the restore itself can be reverted.
+func RestoreSnapshot(snapshot Snapshot, targetPath string) error {
+	lock, err := Lock(targetPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	content, err := os.ReadFile(snapshot.Path)
+	if err != nil {
+		return err
+	}
+	if err := TakeSnapshot(targetPath); err != nil {
+		return err
+	}
+	return WriteFileAtomic(targetPath, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+}
+
+// DiffFiles compares the snapshot with the current file, lines only in the snapshot are marked with '+'
+// because restoring would bring them back, lines only in the current file with '-'
+func DiffFiles(currentPath, snapshotPath string) ([]DiffLine, error) {
+	current, err := readLines(currentPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	snapshot, err := readLines(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	return diffLines(current, snapshot), nil
+}
+
+// parseSnapshotName checks whether the entry is a snapshot of the file and reads its time
+func parseSnapshotName(backupDir, fileName, entryName string) (Snapshot, bool) {
+	prefix := fileName + "."
+	if !strings.HasPrefix(entryName, prefix) || !strings.HasSuffix(entryName, snapshotSuffix) {
+		return Snapshot{}, false
+	}
+	timestamp := strings.TrimSuffix(strings.TrimPrefix(entryName, prefix), snapshotSuffix)
+	snapshotTime, err := time.ParseInLocation(snapshotTimeFormat, timestamp, time.Local)
+	if err != nil {
+		return Snapshot{}, false
+	}
+	return Snapshot{Path: filepath.Join(backupDir, entryName), FileName: fileName, Time: snapshotTime}, true
+}
+
+// pruneSnapshots removes the snapshots of the file that exceed the count or age of the policy
+func pruneSnapshots(backupDir, fileName string) error {
+	snapshots, err := listSnapshotsIn(backupDir, fileName)
+	if err != nil {
+		return err
+	}
+	sortNewestFirst(snapshots)
+	for index, snapshot := range snapshots {
+		tooMany := Backups.MaxCount > 0 && index >= Backups.MaxCount
+		tooOld := Backups.MaxAge > 0 && time.Since(snapshot.Time) > Backups.MaxAge
+		// The newest snapshot is always kept, even if it is old
+		if index > 0 && (tooMany || tooOld) {
+			if err := os.Remove(snapshot.Path); err != nil {
+				return fmt.Errorf("removing old snapshot: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// readLines reads a text file into its lines
+func readLines(filePath string) ([]string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n"), nil
+}
+
+// maxDiffCells limits the table of the longest common subsequence, larger changes are shown as a block of removed
+// lines followed by a block of added lines
+const maxDiffCells = 1 << 20
+
+// diffLines computes a line based diff. The lines both files start and end with are kept as they are,
+// only the lines in between are compared with the longest common subsequence.
+func diffLines(from, to []string) []DiffLine {
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+
+	var diff []DiffLine
+	for _, line := range from[:prefix] {
+		diff = append(diff, DiffLine{Change: ' ', Text: line})
+	}
+	diff = append(diff, diffChangedLines(from[prefix:len(from)-suffix], to[prefix:len(to)-suffix])...)
+	for _, line := range from[len(from)-suffix:] {
+		diff = append(diff, DiffLine{Change: ' ', Text: line})
+	}
+	return diff
+}
+
+// diffChangedLines computes the diff of the lines between the common start and end of both files
+func diffChangedLines(from, to []string) []DiffLine {
+	var diff []DiffLine
+	if (len(from)+1)*(len(to)+1) > maxDiffCells {
+		for _, line := range from {
+			diff = append(diff, DiffLine{Change: '-', Text: line})
+		}
+		for _, line := range to {
+			diff = append(diff, DiffLine{Change: '+', Text: line})
+		}
+		return diff
+	}
+
+	// common[i][j] is the length of the longest common subsequence of from[i:] and to[j:]
+	common := make([][]int, len(from)+1)
+	for i := range common {
+		common[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			diff = append(diff, DiffLine{Change: ' ', Text: from[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			diff = append(diff, DiffLine{Change: '-', Text: from[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Change: '+', Text: to[j]})
+			j++
+		}
+	}
+	for ; i < len(from); i++ {
+		diff = append(diff, DiffLine{Change: '-', Text: from[i]})
+	}
+	for ; j < len(to); j++ {
+		diff = append(diff, DiffLine{Change: '+', Text: to[j]})
+	}
+	return diff
+}
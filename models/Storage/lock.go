@@ -0,0 +1,188 @@
+package Storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// LockFileName is the name of the advisory lock file that guards all data files of a directory
+	LockFileName = "inventar.lock"
+
+	lockRetryInterval = 100 * time.Millisecond
+	lockTimeout       = 10 * time.Second
+	// staleLockAge is the age after which a lock is treated as left over from a crashed process
+	staleLockAge = 2 * time.Minute
+)
+
+// ErrLocked is returned when another process holds the lock for longer than the timeout
+var ErrLocked = errors.New("the inventory files are locked by another user, please try again later")
+
+// heldLocks counts how often this process holds the lock of a directory, so nested calls don't deadlock
+var (
+	heldLocks      = map[string]int{}
+	heldLocksMutex sync.Mutex
+)
+
+// DirLock is an advisory lock on a data directory. It only protects against processes that also use it,
+// but it works on local disks and network shares alike because it is a plain file created exclusively.
+// The file holds a token unique to the holder, so a lock is only ever removed by the one who created it.
+type DirLock struct {
+	path  string
+	token []byte
+}
+
+// heldTokens are the tokens of the lock files this process created, by lock path
+var heldTokens = map[string][]byte{}
+
+// Lock acquires the lock of the directory that contains the passed file, it waits until the lock is free
+func Lock(filePath string) (*DirLock, error) {
+	lockPath := filepath.Join(filepath.Dir(filePath), LockFileName)
+
+	heldLocksMutex.Lock()
+	defer heldLocksMutex.Unlock()
+	if heldLocks[lockPath] > 0 {
+		heldLocks[lockPath]++
+		return &DirLock{path: lockPath, token: heldTokens[lockPath]}, nil
+	}
+
+	token, err := newLockToken()
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, writeErr := file.Write(token)
+			closeErr := file.Close()
+			if writeErr != nil || closeErr != nil {
+				_ = os.Remove(lockPath)
+				return nil, errors.Join(writeErr, closeErr)
+			}
+			heldLocks[lockPath] = 1
+			heldTokens[lockPath] = token
+			return &DirLock{path: lockPath, token: token}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		// A lock that is older than any regular save belongs to a process that crashed. It is only taken over
+		// when it still has the same token, another process may have taken it over in the meantime.
+		if staleToken, readErr := os.ReadFile(lockPath); readErr == nil && isStaleLock(lockPath) {
+			_, _ = removeOwnedLock(lockPath, staleToken, true)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrLocked
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// Unlock releases the lock, the lock file is removed when the outermost holder releases it
+func (l *DirLock) Unlock() error {
+	heldLocksMutex.Lock()
+	defer heldLocksMutex.Unlock()
+
+	heldLocks[l.path]--
+	if heldLocks[l.path] > 0 {
+		return nil
+	}
+	delete(heldLocks, l.path)
+	delete(heldTokens, l.path)
+	removed, err := removeOwnedLock(l.path, l.token, false)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("the lock %s was taken over by another process", l.path)
+	}
+	return nil
+}
+
+// newLockToken returns the content of a new lock file: host, process and time for people looking at it,
+// and a random value that tells apart two holders with the same process ID
+func newLockToken() ([]byte, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	return fmt.Appendf(nil, "%s;%d;%s;%s\n", hostname, os.Getpid(), time.Now().Format(time.RFC3339), hex.EncodeToString(random)), nil
+}
+
+// isStaleLock reports whether the lock file at the path is older than any regular save
+func isStaleLock(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > staleLockAge
+}
+
+// removeOwnedLock removes the lock file when it has the expected token, and with mustBeStale only when it is also
+// stale. The file is first renamed to a path of its own, so the check can't race with another process creating a new
+// lock. A lock that turns out to belong to someone else is put back. False means the lock was not removed.
+func removeOwnedLock(path string, token []byte, mustBeStale bool) (bool, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return false, err
+	}
+	claimed := fmt.Sprintf("%s.%s.release", path, hex.EncodeToString(random))
+	if err := os.Rename(path, claimed); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	content, err := os.ReadFile(claimed)
+	if err == nil && bytes.Equal(content, token) && (!mustBeStale || isStaleLock(claimed)) {
+		return true, os.Remove(claimed)
+	}
+	// Linking fails when a new lock was created in the meantime, that lock is kept then
+	_ = os.Link(claimed, path)
+	return false, os.Remove(claimed)
+}
+
+// Fingerprint identifies a version of a file on disk, it is used to notice changes made by other processes
+type Fingerprint struct {
+	ModTime time.Time
+	Size    int64
+	Hash    [sha256.Size]byte
+}
+
+// FingerprintFile reads the current fingerprint of the passed file
+func FingerprintFile(filePath string) (Fingerprint, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return Fingerprint{}, err
+	}
+
+	fingerprint := Fingerprint{ModTime: info.ModTime(), Size: info.Size()}
+	copy(fingerprint.Hash[:], hash.Sum(nil))
+	return fingerprint, nil
+}
+
+// SameContent reports whether both fingerprints describe the same file content
+func (f Fingerprint) SameContent(other Fingerprint) bool {
+	return f.Size == other.Size && f.Hash == other.Hash
+}
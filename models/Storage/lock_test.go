@@ -0,0 +1,158 @@
+package Storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const foreignToken = "otherhost;4711;2026-01-01T00:00:00Z;0011223344556677\n"
+
+// writeForeignLock creates the lock file of another process in the directory, modified at the passed time
+func writeForeignLock(t *testing.T, dir string, modified time.Time) string {
+	t.Helper()
+	lockPath := filepath.Join(dir, LockFileName)
+	if err := os.WriteFile(lockPath, []byte(foreignToken), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(lockPath, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	return lockPath
+}
+
+// assertOnlyFiles fails when the directory contains other files than the passed ones, e.g. claimed lock files
+func assertOnlyFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, entry := range entries {
+		found = append(found, entry.Name())
+	}
+	if len(found) != len(names) {
+		t.Fatalf("directory contains %v, want %v", found, names)
+	}
+	for index := range names {
+		if found[index] != names[index] {
+			t.Fatalf("directory contains %v, want %v", found, names)
+		}
+	}
+}
+
+func TestLockIsReentrant(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "data.csv")
+	outer, err := Lock(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := Lock(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := inner.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	assertOnlyFiles(t, dir, LockFileName)
+	if err := outer.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	assertOnlyFiles(t, dir)
+}
+
+func TestLockWaitsForForeignToken(t *testing.T) {
+	dir := t.TempDir()
+	lockPath := writeForeignLock(t, dir, time.Now())
+
+	acquired := make(chan *DirLock)
+	go func() {
+		lock, err := Lock(filepath.Join(dir, "data.csv"))
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- lock
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("the lock of another process was taken while it was held")
+	case <-time.After(3 * lockRetryInterval):
+	}
+	if content, _ := os.ReadFile(lockPath); string(content) != foreignToken {
+		t.Fatalf("the lock of another process was changed to %q", content)
+	}
+
+	// Once the other process releases the lock, it is acquired
+	if err := os.Remove(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	lock := <-acquired
+	if lock == nil {
+		return
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	assertOnlyFiles(t, dir)
+}
+
+func TestLockTakesOverStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	lockPath := writeForeignLock(t, dir, time.Now().Add(-staleLockAge-time.Minute))
+
+	lock, err := Lock(filepath.Join(dir, "data.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, lock.token) {
+		t.Fatalf("the lock file contains %q, want the own token %q", content, lock.token)
+	}
+	assertOnlyFiles(t, dir, LockFileName)
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	assertOnlyFiles(t, dir)
+}
+
+func TestUnlockKeepsLockOfAnotherToken(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := Lock(filepath.Join(dir, "data.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another process took the lock over in the meantime, e.g. because this one was suspended for too long
+	lockPath := writeForeignLock(t, dir, time.Now())
+
+	if err := lock.Unlock(); err == nil {
+		t.Error("releasing a lock that was taken over reported no error")
+	}
+	if content, _ := os.ReadFile(lockPath); string(content) != foreignToken {
+		t.Fatalf("the lock of another process was changed to %q", content)
+	}
+	assertOnlyFiles(t, dir, LockFileName)
+}
+
+func TestRemoveOwnedLockKeepsFreshLock(t *testing.T) {
+	dir := t.TempDir()
+	lockPath := writeForeignLock(t, dir, time.Now())
+
+	removed, err := removeOwnedLock(lockPath, []byte(foreignToken), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed {
+		t.Error("a lock that is not stale was removed as stale")
+	}
+	if content, _ := os.ReadFile(lockPath); string(content) != foreignToken {
+		t.Fatalf("the lock was changed to %q", content)
+	}
+	assertOnlyFiles(t, dir, LockFileName)
+}
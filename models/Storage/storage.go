@@ -0,0 +1,238 @@
+package Storage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// JournalFileName is the name of the write-ahead journal, it lives next to the files it protects
+	JournalFileName = "inventar.journal"
+
+	tempSuffix     = ".tmp"
+	journalBegin   = "begin"
+	journalFile    = "file"
+	journalCommit  = "commit"
+	journalDivider = "\t"
+)
+
+// Transaction replaces one or more files together. The caller has to hold the Lock of the directory. Every file is first written to a temporary file and
+// synced to disk, the journal records which temporary file belongs to which target. Only after the journal
+// is marked as committed are the temporary files renamed over the targets, so an interrupted save either
+// leaves the old files in place or can be completed by Recover.
+type Transaction struct {
+	journalPath string
+	journal     *os.File
+	files       []pendingFile
+}
+
+// FileWrite is a file that is written as part of a transaction, Write produces its complete new content
+type FileWrite struct {
+	Path  string
+	Write func(w io.Writer) error
+}
+
+type pendingFile struct {
+	tempPath   string
+	targetPath string
+}
+
+// NewTransaction starts an empty transaction, the journal is created with the first file
+func NewTransaction() *Transaction {
+	return &Transaction{}
+}
+
+// WriteFile writes the content for targetPath to a temporary file and adds it to the transaction
+func (t *Transaction) WriteFile(targetPath string, write func(w io.Writer) error) error {
+	if t.journal == nil {
+		if err := t.openJournal(filepath.Dir(targetPath)); err != nil {
+			return err
+		}
+	}
+
+	tempPath := targetPath + tempSuffix
+	if err := t.appendJournal(journalFile, tempPath, targetPath); err != nil {
+		t.Rollback()
+		return err
+	}
+	t.files = append(t.files, pendingFile{tempPath: tempPath, targetPath: targetPath})
+
+	file, err := os.Create(tempPath)
+	if err != nil {
+		t.Rollback()
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	err = write(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		t.Rollback()
+		return err
+	}
+	return nil
+}
+
+// Commit marks the journal as committed and moves all temporary files over their targets
+func (t *Transaction) Commit() error {
+	if t.journal == nil {
+		return nil // Nothing was written
+	}
+	if err := t.appendJournal(journalCommit); err != nil {
+		t.Rollback()
+		return err
+	}
+	if err := t.journal.Close(); err != nil {
+		return err
+	}
+	t.journal = nil
+
+	// From here on the transaction is durable, a crash is completed by Recover
+	if err := replaceFiles(t.files); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(t.journalPath))
+	return os.Remove(t.journalPath)
+}
+
+// Rollback removes the temporary files and the journal, the target files stay untouched
+func (t *Transaction) Rollback() {
+	if t.journal != nil {
+		_ = t.journal.Close()
+		t.journal = nil
+	}
+	for _, file := range t.files {
+		_ = os.Remove(file.tempPath)
+	}
+	if t.journalPath != "" {
+		_ = os.Remove(t.journalPath)
+	}
+	t.files = nil
+}
+
+// WriteFileAtomic replaces a single file with write, fsync and rename semantics
+func WriteFileAtomic(targetPath string, write func(w io.Writer) error) error {
+	lock, err := Lock(targetPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	transaction := NewTransaction()
+	if err := transaction.WriteFile(targetPath, write); err != nil {
+		return err
+	}
+	return transaction.Commit()
+}
+
+// Recover completes or rolls back a save that was interrupted in the passed directory
+func Recover(dir string) error {
+	journalPath := filepath.Join(dir, JournalFileName)
+	// Hold the lock, otherwise a save that is still running in another process would be rolled back
+	lock, err := Lock(journalPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	content, err := os.ReadFile(journalPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var files []pendingFile
+	committed := false
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Split(line, journalDivider)
+		switch {
+		case fields[0] == journalFile && len(fields) == 3:
+			files = append(files, pendingFile{tempPath: fields[1], targetPath: fields[2]})
+		case fields[0] == journalCommit:
+			committed = true
+		}
+	}
+
+	if committed {
+		// Roll forward: every temporary file that was not renamed yet replaces its target
+		var remaining []pendingFile
+		for _, file := range files {
+			if _, err := os.Stat(file.tempPath); err == nil {
+				remaining = append(remaining, file)
+			}
+		}
+		if err := replaceFiles(remaining); err != nil {
+			return fmt.Errorf("recovering interrupted save: %v", err)
+		}
+	} else {
+		// Roll back: the targets were never touched, only the temporary files are discarded
+		for _, file := range files {
+			if err := os.Remove(file.tempPath); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("rolling back interrupted save: %v", err)
+			}
+		}
+	}
+	syncDir(dir)
+	return os.Remove(journalPath)
+}
+
+// openJournal creates the journal in the passed directory, an existing journal means an unrecovered save
+func (t *Transaction) openJournal(dir string) error {
+	journalPath := filepath.Join(dir, JournalFileName)
+	journal, err := os.OpenFile(journalPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if os.IsExist(err) {
+		return fmt.Errorf("an interrupted save was not recovered yet, restart the application (%s)", journalPath)
+	}
+	if err != nil {
+		return err
+	}
+	t.journalPath = journalPath
+	t.journal = journal
+	if err := t.appendJournal(journalBegin); err != nil {
+		t.Rollback()
+		return err
+	}
+	return nil
+}
+
+// appendJournal writes one line to the journal and syncs it to disk
+func (t *Transaction) appendJournal(fields ...string) error {
+	if _, err := t.journal.WriteString(strings.Join(fields, journalDivider) + "\n"); err != nil {
+		return err
+	}
+	return t.journal.Sync()
+}
+
+// replaceFiles renames all temporary files over their targets
+func replaceFiles(files []pendingFile) error {
+	for _, file := range files {
+		if err := os.Rename(file.tempPath, file.targetPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncDir flushes a directory so renames are durable, not every platform supports this so errors are ignored
+func syncDir(dir string) {
+	directory, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = directory.Sync()
+	_ = directory.Close()
+}
@@ -0,0 +1,121 @@
+package Storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// interruptedSave writes new content for the passed files like a save that stops at the passed point: without commit
+// nothing was renamed yet, after the commit the first renamed files were already moved over their targets.
+func interruptedSave(t *testing.T, targets []string, content string, committed bool, renamed int) {
+	t.Helper()
+	transaction := NewTransaction()
+	for _, target := range targets {
+		err := transaction.WriteFile(target, func(w io.Writer) error {
+			_, err := io.WriteString(w, content)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if committed {
+		if err := transaction.appendJournal(journalCommit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The process stops here, the journal is left behind as it was
+	if err := transaction.journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceFiles(transaction.files[:renamed]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name      string
+		committed bool
+		renamed   int
+		want      string
+	}{
+		{name: "stopped after writing the journal", committed: false, renamed: 0, want: "old"},
+		{name: "stopped after the commit", committed: true, renamed: 0, want: "new"},
+		{name: "stopped after some of the renames", committed: true, renamed: 1, want: "new"},
+		{name: "stopped after all renames", committed: true, renamed: 3, want: "new"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var targets []string
+			for _, name := range []string{"data.csv", "data.csv.seq", "categories.csv"} {
+				target := filepath.Join(dir, name)
+				if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				targets = append(targets, target)
+			}
+			interruptedSave(t, targets, "new", test.committed, test.renamed)
+
+			if err := Recover(dir); err != nil {
+				t.Fatal(err)
+			}
+			for _, target := range targets {
+				content, err := os.ReadFile(target)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(content) != test.want {
+					t.Errorf("%s contains %q after recovery, want %q", filepath.Base(target), content, test.want)
+				}
+				if _, err := os.Stat(target + tempSuffix); !os.IsNotExist(err) {
+					t.Errorf("temporary file of %s was left behind", filepath.Base(target))
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, JournalFileName)); !os.IsNotExist(err) {
+				t.Error("journal was left behind")
+			}
+		})
+	}
+}
+
+func TestRecoverWithoutJournal(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "data.csv")
+	if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Recover(dir); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "old" {
+		t.Errorf("data.csv contains %q, want it untouched", content)
+	}
+}
+
+func TestTransactionRefusesUnrecoveredJournal(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "data.csv")
+	interruptedSave(t, []string{target}, "new", false, 0)
+
+	transaction := NewTransaction()
+	err := transaction.WriteFile(target, func(w io.Writer) error { return nil })
+	if err == nil {
+		t.Fatal("a save was started while an interrupted save was not recovered")
+	}
+	if err := Recover(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(target, func(w io.Writer) error {
+		_, err := io.WriteString(w, "saved")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "saved" {
+		t.Errorf("data.csv contains %q after recovery and save, want %q", content, "saved")
+	}
+}
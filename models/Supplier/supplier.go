@@ -3,12 +3,33 @@ package Supplier
 import (
 	"encoding/csv"
 	"fmt"
+	"io"
+	"it_inventar/models"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Storage"
+	"it_inventar/models/Validation"
+	"net/mail"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 )
 
-// ReadSuppliers reads all categories from the CSV file and returns them as a slice of strings
-func ReadSuppliers(filePath string) ([]string, error) {
+// Columns are the column names of the header row of the supplier file, in the order they are written.
+// Older supplier files have no header and only the name in every row.
+var Columns = []string{
+	"SupplierName",
+	"ContactPerson",
+	"Email",
+	"Phone",
+	"Address",
+	"Website",
+	"CustomerNumber",
+	"PaymentTerms",
+}
+
+// ReadSupplierRecords reads all suppliers with their contact and account data from the CSV file
+func ReadSupplierRecords(filePath string) ([]models.Supplier, error) {
 	// Open the CSV file
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -23,6 +44,7 @@ func ReadSuppliers(filePath string) ([]string, error) {
 
 	// Create a CSV reader and read all rows from the file
 	reader := csv.NewReader(file)
+	reader.Comma = ';'
 	// Allow variable number of fields per record
 	reader.FieldsPerRecord = -1
 	records, err := reader.ReadAll()
@@ -30,89 +52,354 @@ func ReadSuppliers(filePath string) ([]string, error) {
 		return nil, err
 	}
 
-	var suppliers []string
+	// Files with a header row have one column per field, older files only the name
+	columns := map[string]int{Columns[0]: 0}
+	if len(records) > 0 && len(records[0]) > 0 && strings.TrimSpace(records[0][0]) == Columns[0] {
+		columns = map[string]int{}
+		for index, name := range records[0] {
+			columns[strings.TrimSpace(name)] = index
+		}
+		records = records[1:]
+	}
+
+	var suppliers []models.Supplier
 	// Iterate over each record (row) in the CSV file
 	for _, record := range records {
-		if len(record) > 0 {
-			suppliers = append(suppliers, strings.TrimSpace(record[0]))
+		supplier := parseSupplier(columns, record)
+		if supplier.SupplierName != "" {
+			suppliers = append(suppliers, supplier)
 		}
 	}
 	return suppliers, nil
 }
 
-// AddSupplierToFile adds a new category to the CSV file
+// ReadSuppliers reads the names of all suppliers from the CSV file
+func ReadSuppliers(filePath string) ([]string, error) {
+	suppliers, err := ReadSupplierRecords(filePath)
+	return Names(suppliers), err
+}
+
+// Names returns the names of the passed suppliers
+func Names(suppliers []models.Supplier) []string {
+	var names []string
+	for _, supplier := range suppliers {
+		names = append(names, supplier.SupplierName)
+	}
+	return names
+}
+
+// FindSupplier returns the supplier with the passed name
+func FindSupplier(suppliers []models.Supplier, name string) (models.Supplier, bool) {
+	for _, supplier := range suppliers {
+		if supplier.SupplierName == name {
+			return supplier, true
+		}
+	}
+	return models.Supplier{}, false
+}
+
+// AddSupplierToFile adds a new supplier to the CSV file
 func AddSupplierToFile(filePath, supplierName string) error {
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	// Lock the file so a supplier added by another user in the meantime is not lost
+	lock, err := Storage.Lock(filePath)
 	if err != nil {
 		return err
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			fmt.Println("Error closing file:", err)
-		}
-	}(file)
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	defer lock.Unlock()
 
-	if err := writer.Write([]string{supplierName}); err != nil {
+	suppliers, err := ReadSupplierRecords(filePath)
+	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
+	if duplicate, found := Validation.FindDuplicate(supplierName, Names(suppliers)); found {
+		return fmt.Errorf("supplier '%s' already exists", duplicate)
+	}
 
-	return nil
+	if err := OverwriteSupplierFile(filePath, append(suppliers, models.Supplier{SupplierName: supplierName})); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, []Audit.Entry{{Operation: Audit.OpAdd, Entity: Audit.EntitySupplier, Key: supplierName, Field: "Name", After: supplierName}})
 }
 
-// IsValidSupplierName checks if the supplier name meets the validation criteria
-func IsValidSupplierName(name string) bool {
-	if name == "" {
-		return false
+// IsValidEmail checks the email address of a supplier, it may be empty
+func IsValidEmail(email string) bool {
+	if email == "" {
+		return true
 	}
-	for _, char := range name {
-		if !(char >= 'a' && char <= 'z') && !(char >= 'A' && char <= 'Z') && !(char >= '0' && char <= '9') && char != ' ' {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+// IsValidPhone checks the phone number of a supplier, it may be empty
+func IsValidPhone(phone string) bool {
+	for _, char := range phone {
+		if !(char >= '0' && char <= '9') && !strings.ContainsRune(" +-/()", char) {
 			return false
 		}
 	}
 	return true
 }
 
-// DeleteSupplier from existing list
-func DeleteSupplier(filePath string, index int) error {
-	suppliers, err := ReadSuppliers(filePath)
+// DeleteSupplier removes the supplier with the passed name from the existing list. The optional update is applied to
+// the active items of the supplier, e.g. to reassign them, and saved in one transaction with the supplier file.
+func DeleteSupplier(filePath, supplierName string, items models.ItemStore, update func(item *models.Item)) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// Read the current list, it may have been changed by another user since it was displayed
+	suppliers, err := ReadSupplierRecords(filePath)
 	if err != nil {
 		return fmt.Errorf("error reading suppliers: %v", err)
 	}
 
-	// Remove the selected supplier
-	suppliers = append(suppliers[:index], suppliers[index+1:]...)
+	for index, supplier := range suppliers {
+		if supplier.SupplierName == supplierName {
+			// Remove the selected supplier
+			suppliers = append(suppliers[:index], suppliers[index+1:]...)
 
-	// Overwrite the supplier file
-	return OverwriteSupplierFile(filePath, suppliers)
+			entries := []Audit.Entry{{Operation: Audit.OpDelete, Entity: Audit.EntitySupplier, Key: supplierName, Field: "Name", Before: supplierName}}
+			if update == nil {
+				// Overwrite the supplier file
+				if err := OverwriteSupplierFile(filePath, suppliers); err != nil {
+					return err
+				}
+				return Audit.Record(filePath, entries)
+			}
+			var ids []int
+			for _, item := range models.ItemsReferencing(items.GetAllItems(), Audit.EntitySupplier, supplierName) {
+				ids = append(ids, item.ID)
+			}
+			err := items.UpdateItems(ids, update, Storage.FileWrite{Path: filePath, Write: func(w io.Writer) error {
+				return writeSuppliers(w, suppliers)
+			}})
+			if err != nil {
+				return err
+			}
+			return Audit.Record(filePath, entries)
+		}
+	}
+	return fmt.Errorf("supplier '%s' no longer exists, it was probably changed by another user", supplierName)
+}
+
+// UpdateSupplier replaces the contact and account data of the supplier with the same name,
+// every changed field is recorded in the audit log
+func UpdateSupplier(filePath string, updated models.Supplier) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	suppliers, err := ReadSupplierRecords(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading suppliers: %v", err)
+	}
+	index := slices.IndexFunc(suppliers, func(supplier models.Supplier) bool {
+		return supplier.SupplierName == updated.SupplierName
+	})
+	if index < 0 {
+		return fmt.Errorf("supplier '%s' no longer exists, it was probably changed by another user", updated.SupplierName)
+	}
+	previous := suppliers[index]
+	suppliers[index] = updated
+	if err := OverwriteSupplierFile(filePath, suppliers); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, fieldChanges(Audit.OpUpdate, &previous, &updated))
 }
 
-// OverwriteSupplierFile overwrites the content of the given file with the provided list of categories.
-func OverwriteSupplierFile(filePath string, suppliers []string) error {
-	file, err := os.Create(filePath) // Create a new file (overwrite existing)
+// SetSuppliers replaces the list of suppliers, every added, removed or changed supplier is recorded in the audit log
+func SetSuppliers(filePath string, suppliers []models.Supplier) error {
+	lock, err := Storage.Lock(filePath)
 	if err != nil {
 		return err
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
+	defer lock.Unlock()
+
+	_, entries, err := SetSuppliersWrite(filePath, suppliers)
+	if err != nil {
+		return err
+	}
+	if err := OverwriteSupplierFile(filePath, suppliers); err != nil {
+		return err
+	}
+	return Audit.Record(filePath, entries)
+}
+
+// SetSuppliersWrite returns the write that replaces the list of suppliers and the audit entries of the change, so
+// the list can be saved in the same transaction as the items. The caller holds the lock of the supplier file.
+func SetSuppliersWrite(filePath string, suppliers []models.Supplier) (Storage.FileWrite, []Audit.Entry, error) {
+	current, err := ReadSupplierRecords(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return Storage.FileWrite{}, nil, err
+	}
+	write := Storage.FileWrite{Path: filePath, Write: func(w io.Writer) error {
+		return writeSuppliers(w, suppliers)
+	}}
 
+	var entries []Audit.Entry
+	for _, previous := range current {
+		if updated, ok := FindSupplier(suppliers, previous.SupplierName); ok {
+			entries = append(entries, fieldChanges(Audit.OpUpdate, &previous, &updated)...)
+		} else {
+			entries = append(entries, Audit.Entry{Operation: Audit.OpDelete, Entity: Audit.EntitySupplier, Key: previous.SupplierName, Field: "Name", Before: previous.SupplierName})
+		}
+	}
+	for _, supplier := range suppliers {
+		if _, ok := FindSupplier(current, supplier.SupplierName); !ok {
+			entries = append(entries, fieldChanges(Audit.OpAdd, nil, &supplier)...)
 		}
-	}(file)
+	}
+	return write, entries, nil
+}
 
+// OverwriteSupplierFile overwrites the content of the given file with the provided list of suppliers.
+// The file is replaced atomically, so a crash while saving never leaves a half written file behind.
+func OverwriteSupplierFile(filePath string, suppliers []models.Supplier) error {
+	// Keep the previous version, so a wrong change can be restored from the service menu
+	if err := Storage.TakeSnapshot(filePath); err != nil {
+		return err
+	}
+
+	return Storage.WriteFileAtomic(filePath, func(w io.Writer) error {
+		return writeSuppliers(w, suppliers)
+	})
+}
+
+// writeSuppliers writes the header row and the suppliers in the format of the supplier file
+func writeSuppliers(w io.Writer, suppliers []models.Supplier) error {
 	// Initialize a CSV writer to write to the file
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
 
-	// Write each category as a new row in the CSV file
+	if err := writer.Write(Columns); err != nil {
+		return err
+	}
+	// Write each supplier as a new row in the CSV file
 	for _, supplier := range suppliers {
-		err = writer.Write([]string{supplier}) // Write each supplier as a row
-		if err != nil {
+		if err := writer.Write(supplierFields(supplier)); err != nil {
 			return err
 		}
 	}
-	return nil
+	writer.Flush()
+	return writer.Error()
+}
+
+// RenameSupplier renames the supplier in the list and in every item that uses it.
+// The supplier file and the data file of the items are replaced in one transaction.
+func RenameSupplier(filePath, oldName, newName string, items models.ItemStore) error {
+	lock, err := Storage.Lock(filePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	suppliers, err := ReadSupplierRecords(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading suppliers: %v", err)
+	}
+	// Only the case of the name may change, other suppliers must not have the new name
+	others := slices.DeleteFunc(Names(suppliers), func(name string) bool { return name == oldName })
+	if duplicate, found := Validation.FindDuplicate(newName, others); found {
+		return fmt.Errorf("supplier '%s' already exists", duplicate)
+	}
+	index := slices.IndexFunc(suppliers, func(supplier models.Supplier) bool {
+		return supplier.SupplierName == oldName
+	})
+	if index < 0 {
+		return fmt.Errorf("supplier '%s' no longer exists, it was probably changed by another user", oldName)
+	}
+	suppliers[index].SupplierName = newName
+
+	var ids []int
+	for _, item := range models.AllItemsReferencing(items.GetAllItems(), Audit.EntitySupplier, oldName) {
+		ids = append(ids, item.ID)
+	}
+	err = items.UpdateItems(ids, func(item *models.Item) {
+		item.SetReference(Audit.EntitySupplier, newName)
+	}, Storage.FileWrite{Path: filePath, Write: func(w io.Writer) error {
+		return writeSuppliers(w, suppliers)
+	}})
+	if err != nil {
+		return err
+	}
+	return Audit.Record(filePath, []Audit.Entry{{Operation: Audit.OpRename, Entity: Audit.EntitySupplier, Key: oldName, Field: "Name", Before: oldName, After: newName}})
+}
+
+// CreateSupplierFile creates a new supplier file with the passed suppliers, an existing file is never overwritten
+func CreateSupplierFile(filePath string, suppliers []models.Supplier) error {
+	if _, err := os.Stat(filePath); err == nil {
+		return fmt.Errorf("%s already exists", filePath)
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	return OverwriteSupplierFile(filePath, suppliers)
+}
+
+// supplierFields returns the values of the supplier in the order of Columns
+func supplierFields(supplier models.Supplier) []string {
+	return []string{
+		supplier.SupplierName,
+		supplier.ContactPerson,
+		supplier.Email,
+		supplier.Phone,
+		supplier.Address,
+		supplier.Website,
+		supplier.CustomerNumber,
+		supplier.PaymentTerms,
+	}
+}
+
+// parseSupplier reads a supplier from a row, columns maps the column names to their position in the row
+func parseSupplier(columns map[string]int, record []string) models.Supplier {
+	value := func(name string) string {
+		index, ok := columns[name]
+		if !ok || index >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[index])
+	}
+	return models.Supplier{
+		SupplierName:   value("SupplierName"),
+		ContactPerson:  value("ContactPerson"),
+		Email:          value("Email"),
+		Phone:          value("Phone"),
+		Address:        value("Address"),
+		Website:        value("Website"),
+		CustomerNumber: value("CustomerNumber"),
+		PaymentTerms:   value("PaymentTerms"),
+	}
+}
+
+// fieldChanges returns an audit entry for every field that differs, nil stands for a supplier that did not exist
+func fieldChanges(operation string, before, after *models.Supplier) []Audit.Entry {
+	var oldFields, newFields []string
+	key := ""
+	if before != nil {
+		oldFields = supplierFields(*before)
+		key = before.SupplierName
+	}
+	if after != nil {
+		newFields = supplierFields(*after)
+		key = after.SupplierName
+	}
+
+	var entries []Audit.Entry
+	for index, column := range Columns {
+		var oldValue, newValue string
+		if oldFields != nil {
+			oldValue = oldFields[index]
+		}
+		if newFields != nil {
+			newValue = newFields[index]
+		}
+		if oldValue != newValue {
+			entries = append(entries, Audit.Entry{Operation: operation, Entity: Audit.EntitySupplier, Key: key, Field: column, Before: oldValue, After: newValue})
+		}
+	}
+	return entries
 }
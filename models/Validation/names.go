@@ -0,0 +1,72 @@
+package Validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultPunctuation are the characters names may contain besides letters, digits and spaces.
+// They cover the names already found in our files, like "Computer/PCs" or "Brack.ch".
+const DefaultPunctuation = "/.-&+()'_"
+
+// DefaultMaxLength is the maximum number of characters of a name
+const DefaultMaxLength = 50
+
+// NameRules decide which names are accepted for categories, suppliers and attributes
+type NameRules struct {
+	// Punctuation are the characters allowed besides Unicode letters, digits and spaces
+	Punctuation string
+	// MaxLength is the maximum number of characters, 0 allows any length
+	MaxLength int
+}
+
+// DefaultNameRules are the rules used when nothing else is configured
+var DefaultNameRules = NameRules{Punctuation: DefaultPunctuation, MaxLength: DefaultMaxLength}
+
+// Normalize trims the name and collapses runs of spaces inside it to a single space
+func Normalize(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Check normalizes the name and checks it against the rules and the existing names.
+// Names differing only in case are duplicates. It returns the normalized name, or an error that tells the user
+// what is wrong with it.
+func (r NameRules) Check(name string, existing []string) (string, error) {
+	name = Normalize(name)
+	if name == "" {
+		return "", fmt.Errorf("the name must not be empty")
+	}
+	if r.MaxLength > 0 && utf8.RuneCountInString(name) > r.MaxLength {
+		return "", fmt.Errorf("the name must not be longer than %d characters", r.MaxLength)
+	}
+	for _, char := range name {
+		if !unicode.IsLetter(char) && !unicode.IsDigit(char) && !unicode.IsMark(char) && char != ' ' && !strings.ContainsRune(r.Punctuation, char) {
+			return "", fmt.Errorf("'%c' is not allowed, please use only %s", char, r.Allowed())
+		}
+	}
+	if duplicate, found := FindDuplicate(name, existing); found {
+		return "", fmt.Errorf("'%s' already exists", duplicate)
+	}
+	return name, nil
+}
+
+// Allowed describes the characters the rules accept, for messages to the user
+func (r NameRules) Allowed() string {
+	if r.Punctuation == "" {
+		return "letters, digits and spaces"
+	}
+	return fmt.Sprintf("letters, digits, spaces and %s", strings.Join(strings.Split(r.Punctuation, ""), " "))
+}
+
+// FindDuplicate returns the existing name that equals the name when case and surrounding spaces are ignored
+func FindDuplicate(name string, existing []string) (string, bool) {
+	name = Normalize(name)
+	for _, other := range existing {
+		if strings.EqualFold(Normalize(other), name) {
+			return other, true
+		}
+	}
+	return "", false
+}
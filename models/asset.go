@@ -0,0 +1,180 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Status of an asset
+const (
+	AssetInStock   = "in stock"
+	AssetBookedOut = "booked out"
+	AssetDefective = "defective"
+	AssetRetired   = "retired"
+	// AssetCheckedOut is a piece an employee has, it is only set by checking the piece out
+	AssetCheckedOut = "checked out"
+)
+
+// AssetStatuses are the statuses of an asset that can be set by hand, in the order they are offered
+var AssetStatuses = []string{AssetInStock, AssetBookedOut, AssetDefective, AssetRetired}
+
+// AssetDateFormat is the format of the purchase date, as it is entered and stored
+const AssetDateFormat = "02.01.2006"
+
+// Asset is one physical unit of an article, identified by its serial number
+type Asset struct {
+	SerialNumber string `json:"serial"`
+	PurchaseDate string `json:"purchased,omitempty"`
+	Status       string `json:"status"`
+	Note         string `json:"note,omitempty"`
+	// Employee has the piece while it is checked out
+	Employee string `json:"employee,omitempty"`
+}
+
+// *TracksAssets: Reports whether the serial numbers of the pieces of the item are tracked. The quantity of such an item
+// is the number of its assets in stock.
+// *TracksAssets: Gibt zurück, ob die Seriennummern der Stücke des Artikels erfasst werden. Die Menge eines solchen
+// Artikels ist die Anzahl seiner Geräte an Lager.
+func (item Item) TracksAssets() bool {
+	return len(item.Assets) > 0
+}
+
+// *AssetsWithStatus: Returns the assets of the item that have the passed status.
+// *AssetsWithStatus: Gibt die Geräte des Artikels zurück, welche den übergebenen Status haben.
+func (item Item) AssetsWithStatus(status string) []Asset {
+	var found []Asset
+	for _, asset := range item.Assets {
+		if asset.Status == status {
+			found = append(found, asset)
+		}
+	}
+	return found
+}
+
+// *SetAssetStatus: Gives the assets with the passed serial numbers the new status and updates the quantity.
+// The assets are replaced as a whole, so copies of the item keep their assets. Checking out sets the employee as well.
+// *SetAssetStatus: Gibt den Geräten mit den übergebenen Seriennummern den neuen Status und aktualisiert die Menge.
+// Die Geräte werden als Ganzes ersetzt, damit Kopien des Artikels ihre Geräte behalten.
+func (item *Item) SetAssetStatus(serialNumbers []string, status string) error {
+	assets := slices.Clone(item.Assets)
+	for _, serialNumber := range serialNumbers {
+		index := item.assetIndex(serialNumber)
+		if index < 0 {
+			return fmt.Errorf("%s has no serial number %s", item.ArticleName, serialNumber)
+		}
+		assets[index].Status = status
+		assets[index].Employee = ""
+	}
+	item.Assets = assets
+	item.syncQuantity()
+	return nil
+}
+
+// *UpdateAsset: Replaces the asset with the serial number of the passed one and updates the quantity.
+// *UpdateAsset: Ersetzt das Gerät mit der Seriennummer des übergebenen und aktualisiert die Menge.
+func (item *Item) UpdateAsset(asset Asset) error {
+	index := item.assetIndex(asset.SerialNumber)
+	if index < 0 {
+		return fmt.Errorf("%s has no serial number %s", item.ArticleName, asset.SerialNumber)
+	}
+	if err := asset.Validate(); err != nil {
+		return err
+	}
+	if asset.Status != AssetCheckedOut {
+		asset.Employee = ""
+	}
+	assets := slices.Clone(item.Assets)
+	assets[index] = asset
+	item.Assets = assets
+	item.syncQuantity()
+	return nil
+}
+
+// *AddAssets: Adds new assets to the item and updates the quantity, a serial number can only be added once.
+// *AddAssets: Fügt dem Artikel neue Geräte hinzu und aktualisiert die Menge, eine Seriennummer kann nur einmal hinzugefügt werden.
+func (item *Item) AddAssets(assets ...Asset) error {
+	added := slices.Clone(item.Assets)
+	for _, asset := range assets {
+		if err := asset.Validate(); err != nil {
+			return err
+		}
+		if slices.ContainsFunc(added, func(other Asset) bool { return SameSerialNumber(other.SerialNumber, asset.SerialNumber) }) {
+			return fmt.Errorf("%s already has the serial number %s", item.ArticleName, asset.SerialNumber)
+		}
+		added = append(added, asset)
+	}
+	item.Assets = added
+	item.syncQuantity()
+	return nil
+}
+
+// *Validate: Checks the serial number, the purchase date and the status of the asset.
+// *Validate: Prüft die Seriennummer, das Kaufdatum und den Status des Geräts.
+func (asset Asset) Validate() error {
+	if strings.TrimSpace(asset.SerialNumber) == "" {
+		return fmt.Errorf("the serial number must not be empty")
+	}
+	if asset.PurchaseDate != "" {
+		if _, err := time.Parse(AssetDateFormat, asset.PurchaseDate); err != nil {
+			return fmt.Errorf("the purchase date must be a date like 31.12.2024")
+		}
+	}
+	if asset.Status == AssetCheckedOut {
+		if asset.Employee == "" {
+			return fmt.Errorf("a checked out piece needs the employee who has it")
+		}
+	} else if !slices.Contains(AssetStatuses, asset.Status) {
+		return fmt.Errorf("unknown asset status %q", asset.Status)
+	}
+	return nil
+}
+
+// *SameSerialNumber: Reports whether both serial numbers are equal when case and surrounding spaces are ignored.
+// *SameSerialNumber: Gibt zurück, ob beide Seriennummern ohne Beachtung von Gross-/Kleinschreibung und Leerzeichen gleich sind.
+func SameSerialNumber(first, second string) bool {
+	return strings.EqualFold(strings.TrimSpace(first), strings.TrimSpace(second))
+}
+
+// *FindSerialNumber: Returns the item other than excludeID that has an asset with the serial number, deleted items included.
+// *FindSerialNumber: Gibt den Artikel ausser excludeID zurück, der ein Gerät mit der Seriennummer hat, gelöschte eingeschlossen.
+func FindSerialNumber(items []Item, serialNumber string, excludeID int) (Item, bool) {
+	for _, item := range items {
+		if item.ID != excludeID && item.assetIndex(serialNumber) >= 0 {
+			return item, true
+		}
+	}
+	return Item{}, false
+}
+
+// *assetIndex: Returns the position of the asset with the serial number, -1 if the item has none.
+// *assetIndex: Gibt die Position des Geräts mit der Seriennummer zurück, -1 falls der Artikel keines hat.
+func (item Item) assetIndex(serialNumber string) int {
+	return slices.IndexFunc(item.Assets, func(asset Asset) bool { return SameSerialNumber(asset.SerialNumber, serialNumber) })
+}
+
+// *syncQuantity: Sets the quantity of an item with assets to the number of its assets in stock.
+// *syncQuantity: Setzt die Menge eines Artikels mit Geräten auf die Anzahl seiner Geräte an Lager.
+func (item *Item) syncQuantity() {
+	if item.TracksAssets() {
+		item.Quantity = len(item.AssetsWithStatus(AssetInStock))
+	}
+}
+
+// *checkSerialNumbers: Returns an error when a serial number the item did not have before is used by another item.
+// *checkSerialNumbers: Gibt einen Fehler zurück, wenn eine Seriennummer, die der Artikel vorher nicht hatte, von einem anderen Artikel verwendet wird.
+func (s *MemoryItemStore) checkSerialNumbers(item Item, before Item) error {
+	for _, asset := range item.Assets {
+		if before.assetIndex(asset.SerialNumber) >= 0 {
+			continue
+		}
+		if err := asset.Validate(); err != nil {
+			return err
+		}
+		if existing, found := FindSerialNumber(s.items, asset.SerialNumber, item.ID); found {
+			return fmt.Errorf("serial number %s is already used by %s (ID %d)", asset.SerialNumber, existing.ArticleName, existing.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Holding is what an employee has of an item: pieces without serial number are counted, the others are listed
+type Holding struct {
+	Employee      string
+	Item          Item
+	Count         int
+	SerialNumbers []string
+}
+
+// *CheckOut: Gives pieces in stock to the employee. Items with assets check out the pieces with the passed serial
+// numbers, other items the passed count, which is taken from the quantity.
+// *CheckOut: Gibt Stücke an Lager an den Mitarbeiter aus. Artikel mit Geräten geben die Stücke mit den übergebenen
+// Seriennummern aus, andere Artikel die übergebene Anzahl, die von der Menge abgezogen wird.
+func (item *Item) CheckOut(employee string, serialNumbers []string, count int) error {
+	if employee == "" {
+		return fmt.Errorf("no employee was chosen")
+	}
+	if item.TracksAssets() {
+		for _, serialNumber := range serialNumbers {
+			index := item.assetIndex(serialNumber)
+			if index < 0 || item.Assets[index].Status != AssetInStock {
+				return fmt.Errorf("%s is not in stock", serialNumber)
+			}
+		}
+		if err := item.SetAssetStatus(serialNumbers, AssetCheckedOut); err != nil {
+			return err
+		}
+		for _, serialNumber := range serialNumbers {
+			item.Assets[item.assetIndex(serialNumber)].Employee = employee
+		}
+		return nil
+	}
+
+	if count < 1 || count > item.Quantity {
+		return fmt.Errorf("only %d pieces of %s are in stock", item.Quantity, item.ArticleName)
+	}
+	checkedOut := maps.Clone(item.CheckedOut)
+	if checkedOut == nil {
+		checkedOut = map[string]int{}
+	}
+	checkedOut[employee] += count
+	item.CheckedOut = checkedOut
+	item.Quantity -= count
+	return nil
+}
+
+// *CheckIn: Takes pieces back from the employee into stock, like CheckOut by serial number or by count.
+// *CheckIn: Nimmt Stücke vom Mitarbeiter zurück an Lager, wie bei CheckOut nach Seriennummer oder nach Anzahl.
+func (item *Item) CheckIn(employee string, serialNumbers []string, count int) error {
+	if item.TracksAssets() {
+		for _, serialNumber := range serialNumbers {
+			index := item.assetIndex(serialNumber)
+			if index < 0 || item.Assets[index].Status != AssetCheckedOut || item.Assets[index].Employee != employee {
+				return fmt.Errorf("%s is not checked out to %s", serialNumber, employee)
+			}
+		}
+		return item.SetAssetStatus(serialNumbers, AssetInStock)
+	}
+
+	if count < 1 || count > item.CheckedOut[employee] {
+		return fmt.Errorf("%s has %d pieces of %s", employee, item.CheckedOut[employee], item.ArticleName)
+	}
+	checkedOut := maps.Clone(item.CheckedOut)
+	checkedOut[employee] -= count
+	if checkedOut[employee] == 0 {
+		delete(checkedOut, employee)
+	}
+	if len(checkedOut) == 0 {
+		checkedOut = nil
+	}
+	item.CheckedOut = checkedOut
+	item.Quantity += count
+	return nil
+}
+
+// *Holdings: Returns who has pieces of the item, sorted by employee.
+// *Holdings: Gibt zurück, wer Stücke des Artikels hat, sortiert nach Mitarbeiter.
+func (item Item) Holdings() []Holding {
+	byEmployee := map[string]*Holding{}
+	holding := func(employee string) *Holding {
+		if byEmployee[employee] == nil {
+			byEmployee[employee] = &Holding{Employee: employee, Item: item}
+		}
+		return byEmployee[employee]
+	}
+	for employee, count := range item.CheckedOut {
+		holding(employee).Count += count
+	}
+	for _, asset := range item.AssetsWithStatus(AssetCheckedOut) {
+		current := holding(asset.Employee)
+		current.Count++
+		current.SerialNumbers = append(current.SerialNumbers, asset.SerialNumber)
+	}
+
+	var holdings []Holding
+	for _, employee := range slices.Sorted(maps.Keys(byEmployee)) {
+		holdings = append(holdings, *byEmployee[employee])
+	}
+	return holdings
+}
+
+// *HoldingsOf: Returns what the employee has of the items, sorted by article name.
+// *HoldingsOf: Gibt zurück, was der Mitarbeiter von den Artikeln hat, sortiert nach Artikelname.
+func HoldingsOf(items []Item, employee string) []Holding {
+	var holdings []Holding
+	for _, item := range items {
+		for _, holding := range item.Holdings() {
+			if holding.Employee == employee {
+				holdings = append(holdings, holding)
+			}
+		}
+	}
+	slices.SortStableFunc(holdings, func(first, second Holding) int {
+		return cmp.Compare(first.Item.ArticleName, second.Item.ArticleName)
+	})
+	return holdings
+}
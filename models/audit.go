@@ -0,0 +1,75 @@
+package models
+
+import (
+	"it_inventar/models/Audit"
+)
+
+// *itemAuditEntries: Compares the items before and after a change and returns one audit entry per changed field.
+// Added items list all their fields, purged items all the fields they had.
+// *itemAuditEntries: Vergleicht die Artikel vor und nach einer Änderung und gibt pro geändertem Feld einen Protokolleintrag zurück.
+// Hinzugefügte Artikel enthalten alle ihre Felder, endgültig entfernte alle Felder, die sie hatten.
+func itemAuditEntries(before, after []Item) []Audit.Entry {
+	previous := make(map[int]Item, len(before))
+	for _, item := range before {
+		previous[item.ID] = item
+	}
+
+	var entries []Audit.Entry
+	for _, item := range after {
+		old, existed := previous[item.ID]
+		delete(previous, item.ID)
+
+		operation := Audit.OpUpdate
+		switch {
+		case !existed:
+			operation = Audit.OpAdd
+		case !old.IsDeleted && item.IsDeleted:
+			operation = Audit.OpDelete
+		case old.IsDeleted && !item.IsDeleted:
+			operation = Audit.OpRestore
+		}
+		var oldFields []string
+		if existed {
+			oldFields = getItemAsStringSlice(old)
+		}
+		entries = append(entries, fieldChanges(operation, item.ID, oldFields, getItemAsStringSlice(item))...)
+	}
+
+	// Items that are left over were purged, they keep the order of the inventory
+	for _, item := range before {
+		if _, purged := previous[item.ID]; purged {
+			entries = append(entries, fieldChanges(Audit.OpPurge, item.ID, getItemAsStringSlice(item), nil)...)
+		}
+	}
+	return entries
+}
+
+// *fieldChanges: Returns an audit entry for every column whose value differs, the ID column is the key and skipped.
+// *fieldChanges: Gibt für jede Spalte mit abweichendem Wert einen Protokolleintrag zurück, die ID-Spalte ist der Schlüssel und wird übersprungen.
+func fieldChanges(operation string, id int, before, after []string) []Audit.Entry {
+	var entries []Audit.Entry
+	for index, column := range ItemColumns {
+		if column == "ID" {
+			continue
+		}
+		var oldValue, newValue string
+		if before != nil {
+			oldValue = before[index]
+		}
+		if after != nil {
+			newValue = after[index]
+		}
+		if oldValue == newValue {
+			continue
+		}
+		entries = append(entries, Audit.Entry{
+			Operation: operation,
+			Entity:    Audit.EntityItem,
+			Key:       IntToString(id),
+			Field:     column,
+			Before:    oldValue,
+			After:     newValue,
+		})
+	}
+	return entries
+}
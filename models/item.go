@@ -4,10 +4,13 @@ package models
 
 import (
 	"encoding/csv"
-	"errors"
+	"encoding/json"
 	"fmt"
+	"io"
+	"it_inventar/models/Storage"
 	"os"
-	"reflect"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -15,6 +18,7 @@ import (
 
 // Item as type
 type Item struct {
+	ID            int
 	ArticleName   string
 	Category      string
 	ArticleNumber string
@@ -23,13 +27,54 @@ type Item struct {
 	Note          string
 	DeleteDate    *time.Time
 	IsDeleted     bool
+	// Attributes are the values of the attributes defined for the category, by attribute name.
+	// The map is shared by copies of the item, so it is always replaced as a whole and never changed in place.
+	Attributes map[string]string
+	// Assets are the pieces of the item with their serial numbers, items without assets only count their quantity.
+	// Like the attributes, the slice is always replaced as a whole.
+	Assets []Asset
+	// CheckedOut are the pieces without serial number that employees have, by employee name. They are not part of
+	// the quantity in stock. Like the attributes, the map is always replaced as a whole.
+	CheckedOut map[string]int
+	// Locations are the pieces in stock per location, the rest of the quantity has no location yet. Like the
+	// attributes, the map is always replaced as a whole.
+	Locations map[string]int
+	// StockLevel is the minimum and reorder quantity of the item, without a level of its own the one of the category
+	// applies. An own minimum of 0 turns the minimum of the category off.
+	StockLevel StockLevel
 }
 
+const FileData = "data.csv"
+const FileCategories = "categories.csv"
+const FileSupplier = "supplier.csv"
+
+// Supplier is a supplier with the contact and account data of our company at the supplier
 type Supplier struct {
-	SupplierName string
+	SupplierName   string
+	ContactPerson  string
+	Email          string
+	Phone          string
+	Address        string
+	Website        string
+	CustomerNumber string
+	PaymentTerms   string
+}
+
+// Employee is a member of staff who can be given equipment
+type Employee struct {
+	EmployeeName string
+	Department   string
+	Email        string
 }
+
+// Category is a category of items, Parent is the name of the category it belongs to, empty on the top level.
+// Numbers is the pattern of the article numbers of its items, subcategories without one use the pattern of their parent.
+// StockLevel is the default minimum and reorder quantity of its items, it is inherited the same way.
 type Category struct {
 	CategoryName string
+	Parent       string
+	Numbers      NumberPattern
+	StockLevel   StockLevel
 }
 
 // GetActiveItems returns a slice of items that are not deleted.
@@ -65,110 +110,196 @@ func IntToString(value int) string {
 	return strconv.Itoa(value)
 }
 
-// *getDataFromDataFile: Reads item data from a CSV file.
-// *getDataFromDataFile: Liest Artikeldaten aus einer CSV-Datei.
-func getDataFromDataFile() ([]Item, error) {
-	file, err := os.Open(FileData)
+// *readItemsFromFile: Reads item data from a CSV file and upgrades older schema versions while reading.
+// It returns the schema version the file had on disk and the rows that could not be loaded.
+// *readItemsFromFile: Liest Artikeldaten aus einer CSV-Datei und aktualisiert ältere Schema-Versionen beim Lesen.
+// Gibt die Schema-Version, welche die Datei auf der Festplatte hatte, und die nicht ladbaren Zeilen zurück.
+func readItemsFromFile(filePath string) ([]Item, int, []RowIssue, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, err
+		return nil, 0, nil, err
 	}
 	defer file.Close()
 
-	csvReader := csv.NewReader(file)
-	csvReader.Comma = ';'
-	csvReader.FieldsPerRecord = reflect.TypeOf(Item{}).NumField()
-	records, err := csvReader.ReadAll()
+	table, err := readDataTable(file)
 	if err != nil {
-		return nil, err
+		return nil, 0, nil, err
+	}
+	fileVersion := table.version
+	if err := migrateTable(table); err != nil {
+		return nil, 0, nil, err
 	}
 
+	columns := columnIndexes(table.header)
+	usedIds := map[int]bool{}
 	var readItems []Item
-	for _, record := range records {
+	for _, row := range table.rows {
 		// Nutzung der angepassten Funktion zum Parsen der Zeile
-		parsedItem, err := ParseItemFromCsvStringList(record)
+		parsedItem, err := ParseItemFromCsvStringList(columns, row.fields)
 		if err != nil {
-			return nil, err
+			table.quarantine(row, err.Error())
+			continue
 		}
+		if parsedItem.ID != 0 && usedIds[parsedItem.ID] {
+			table.quarantine(row, fmt.Sprintf("duplicate item ID %d", parsedItem.ID))
+			continue
+		}
+		usedIds[parsedItem.ID] = true
 		readItems = append(readItems, parsedItem) // Add parsedItem to slice
 	}
 
-	return readItems, nil
+	return readItems, fileVersion, table.issues, nil
 }
 
-// *updateDataInFile: Writes updated item data to a CSV file.
-// *updateDataInFile:Schreibt aktualisierte Artikeldaten in eine CSV-Datei.
-func updateDataInFile() error {
-	file, err := os.Create(FileData)
-	if err != nil {
-		return err
-	}
-	writer := csv.NewWriter(file)
+// *writeItems: Writes the version line, the header row and the passed items as CSV rows.
+// *writeItems: Schreibt die Versionszeile, die Kopfzeile und die übergebenen Artikel als CSV-Zeilen.
+func writeItems(w io.Writer, items []Item) error {
+	writer := csv.NewWriter(w)
 	writer.Comma = ';'
 
+	if err := writeSchemaHeader(writer); err != nil {
+		return err
+	}
 	for _, item := range items {
 		itemRecord := getItemAsStringSlice(item)
 		err := writer.Write(itemRecord)
 		if err != nil {
-			_ = file.Close()
 			return err
 		}
 	}
 
 	writer.Flush()
-	err = file.Close()
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return writer.Error()
 }
 
-// *ParseItemFromCsvStringList: Parses a CSV row and creates an Item.
-// *ParseItemFromCsvStringList: Verarbeitet eine CSV-Zeile und erstellt ein Item.
-func ParseItemFromCsvStringList(record []string) (Item, error) {
+// *ParseItemFromCsvStringList: Parses a CSV row and creates an Item, the columns are looked up by their name.
+// Columns missing in the header keep the zero value.
+// *ParseItemFromCsvStringList: Verarbeitet eine CSV-Zeile und erstellt ein Item, die Spalten werden über ihren Namen gefunden.
+// Spalten, die in der Kopfzeile fehlen, behalten den Nullwert.
+func ParseItemFromCsvStringList(columns map[string]int, record []string) (Item, error) {
 	parsedItem := Item{}
-	numberOfReceivedElements := len(record)
-	numberOfFieldsInBookStruct := reflect.TypeOf(Item{}).NumField()
+	if len(record) != len(columns) {
+		return parsedItem, fmt.Errorf("data record has %d columns, the header has %d", len(record), len(columns))
+	}
+	value := func(column string) string {
+		if index, ok := columns[column]; ok {
+			return strings.TrimSpace(record[index])
+		}
+		return ""
+	}
 
-	if numberOfReceivedElements != numberOfFieldsInBookStruct {
-		err := fmt.Errorf("data record does not contain enough elements for parsing: received %d, expected: %d",
-			numberOfReceivedElements, numberOfFieldsInBookStruct)
-		return parsedItem, err
+	var id int
+	if value("ID") != "" {
+		parsedId, err := strconv.Atoi(value("ID"))
+		if err != nil || parsedId < 1 {
+			return parsedItem, fmt.Errorf("invalid item ID %q", value("ID"))
+		}
+		id = parsedId
 	}
 
 	var deleteDate *time.Time
-	if record[6] != "" {
-		parsedTime, err := time.Parse(time.RFC3339, record[6])
+	if value("DeleteDate") != "" {
+		parsedTime, err := time.Parse(time.RFC3339, value("DeleteDate"))
 		if err != nil {
 			return parsedItem, err
 		}
 		deleteDate = &parsedTime
 	}
 
-	// Create new book based on parsed values
+	var attributes map[string]string
+	if value("Attributes") != "" {
+		if err := json.Unmarshal([]byte(value("Attributes")), &attributes); err != nil {
+			return parsedItem, fmt.Errorf("invalid attributes %q", value("Attributes"))
+		}
+	}
+
+	var assets []Asset
+	if value("Assets") != "" {
+		if err := json.Unmarshal([]byte(value("Assets")), &assets); err != nil {
+			return parsedItem, fmt.Errorf("invalid assets %q", value("Assets"))
+		}
+	}
+
+	var checkedOut map[string]int
+	if value("CheckedOut") != "" {
+		if err := json.Unmarshal([]byte(value("CheckedOut")), &checkedOut); err != nil {
+			return parsedItem, fmt.Errorf("invalid checked out pieces %q", value("CheckedOut"))
+		}
+	}
+
+	var locations map[string]int
+	if value("Locations") != "" {
+		if err := json.Unmarshal([]byte(value("Locations")), &locations); err != nil {
+			return parsedItem, fmt.Errorf("invalid locations %q", value("Locations"))
+		}
+	}
+
+	// Create new item based on parsed values
 	parsedItem = Item{
-		ArticleName:   strings.TrimSpace(record[0]),
-		Category:      strings.TrimSpace(record[1]),
-		ArticleNumber: strings.TrimSpace(record[2]),
-		Supplier:      strings.TrimSpace(record[3]),
-		Quantity:      StringToInt(record[4]),
-		Note:          strings.TrimSpace(record[5]),
+		ID:            id,
+		ArticleName:   value("ArticleName"),
+		Category:      value("Category"),
+		ArticleNumber: value("ArticleNumber"),
+		Supplier:      value("Supplier"),
+		Quantity:      StringToInt(value("Quantity")),
+		Note:          value("Note"),
 		DeleteDate:    deleteDate,
-		IsDeleted:     record[7] == "true", // Korrekte Zuordnung des IsDeleted-Feldes
+		IsDeleted:     value("IsDeleted") == "true", // Korrekte Zuordnung des IsDeleted-Feldes
+		Attributes:    attributes,
+		Assets:        assets,
+		CheckedOut:    checkedOut,
+		Locations:     locations,
+		StockLevel:    ParseStockLevel(value("MinimumQuantity"), value("ReorderQuantity")),
 	}
+	// The quantity of an item with assets is derived from them, in case the file was edited by hand
+	parsedItem.syncQuantity()
+	parsedItem.fitLocations()
 
 	return parsedItem, nil
 }
 
-// *getItemAsStringSlice: Converts an Item to a slice of strings.
-// *getItemAsStringSlice: Konvertiert ein Item in ein String-Array.
+// *getItemAsStringSlice: Converts an Item to a slice of strings in the order of ItemColumns.
+// *getItemAsStringSlice: Konvertiert ein Item in ein String-Array in der Reihenfolge von ItemColumns.
 func getItemAsStringSlice(item Item) []string {
 	var deleteDate string
 	if item.DeleteDate != nil {
 		deleteDate = item.DeleteDate.Format(time.RFC3339)
 	}
 
+	// The attributes are written as a JSON object, its keys are sorted, so equal attributes give equal rows
+	var attributes string
+	if len(item.Attributes) > 0 {
+		encoded, _ := json.Marshal(item.Attributes)
+		attributes = string(encoded)
+	}
+
+	var assets string
+	if len(item.Assets) > 0 {
+		encoded, _ := json.Marshal(item.Assets)
+		assets = string(encoded)
+	}
+
+	var checkedOut string
+	if len(item.CheckedOut) > 0 {
+		encoded, _ := json.Marshal(item.CheckedOut)
+		checkedOut = string(encoded)
+	}
+
+	var locations string
+	if len(item.Locations) > 0 {
+		encoded, _ := json.Marshal(item.Locations)
+		locations = string(encoded)
+	}
+
+	// Items without a level of their own leave both columns empty, an own minimum of 0 is written as 0
+	var minimum, reorder string
+	if item.StockLevel.IsDefined() {
+		minimum = IntToString(item.StockLevel.Minimum)
+		reorder = IntToString(item.StockLevel.Reorder)
+	}
+
 	itemSerialized := []string{
+		IntToString(item.ID),
 		item.ArticleName,
 		item.Category,
 		item.ArticleNumber,
@@ -177,70 +308,107 @@ func getItemAsStringSlice(item Item) []string {
 		item.Note,
 		deleteDate,
 		strconv.FormatBool(item.IsDeleted),
+		attributes,
+		assets,
+		checkedOut,
+		locations,
+		minimum,
+		reorder,
 	}
 
 	return itemSerialized
 }
 
-// *UpdateItem: Updates an item in the inventory.
-// *UpdateItem: aktualisiert einen Artikel im Inventar
-func UpdateItem(id int, updatedItem Item) error {
-	if id < 0 || id >= len(items) {
-		return errors.New("invalid ID")
-	}
-
-	items[id] = updatedItem
-	return updateDataInFile()
+// *Equal: Compares two items by the values written to the data file, so a reloaded item equals the item that was saved.
+// *Equal: Vergleicht zwei Artikel anhand der in die Datendatei geschriebenen Werte, damit ein neu geladener Artikel dem gespeicherten entspricht.
+func (item Item) Equal(other Item) bool {
+	return slices.Equal(getItemAsStringSlice(item), getItemAsStringSlice(other))
 }
 
-const FileData = "data.csv"
-const FileCategories = "categories.csv"
-const FileSupplier = "supplier.csv"
-
-var items []Item
-
-// *AddItem: adds the passed Item to the Inventory
-// *AddItem: Fügt den übergebenen Artikel dem Inventar hinzu.
-func AddItem(newItem Item) error {
-	// Add to item Repository
-	items = append(items, newItem)
-
-	// Update data in file
-	return updateDataInFile()
+// *sequenceFilePath: Returns the path of the file that stores the next free item ID.
+// *sequenceFilePath: Gibt den Pfad der Datei zurück, welche die nächste freie Artikel-ID speichert.
+func sequenceFilePath(dataFilePath string) string {
+	return dataFilePath + ".seq"
 }
 
-// *Initialize: does the initialization of the repository.
-// *Initialize: Initialisiert das Repository.
-func Initialize() error {
-	var err error
-	// Initialisieren Artikel
-	items, err = getDataFromDataFile()
+// *readNextID: Reads the next free item ID, 0 if it was never written.
+// *readNextID: Liest die nächste freie Artikel-ID, 0 falls sie noch nie geschrieben wurde.
+func readNextID(dataFilePath string) (int, error) {
+	content, err := os.ReadFile(sequenceFilePath(dataFilePath))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
 	if err != nil {
-		return err
+		return 0, err
+	}
+	nextID, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid item ID sequence in %s: %v", sequenceFilePath(dataFilePath), err)
 	}
-	return nil
+	return nextID, nil
 }
 
-// *GetAllItems: returns a copy of all items
-// *GetAllItems: Gibt eine Kopie aller Artikel zurück.
-func GetAllItems() []Item {
-	allItems := make([]Item, len(items))
-	copy(allItems, items)
-	return allItems
+// *writeNextID: Stores the next free item ID so IDs are never reused.
+// *writeNextID: Speichert die nächste freie Artikel-ID, damit IDs nie wiederverwendet werden.
+func writeNextID(w io.Writer, nextID int) error {
+	_, err := io.WriteString(w, IntToString(nextID)+"\n")
+	return err
 }
 
-// *RemoveItem: removes the passed row ID from the library
-// *RemoveItem: Entfernt die übergebene Zeilen-ID aus dem Inventar.
-func RemoveItem(rowId int) error {
+// *CreateDataFile: Creates an empty data file that only contains the header of the current schema.
+// *CreateDataFile: Erstellt eine leere Datendatei, die nur die Kopfzeile des aktuellen Schemas enthält.
+func CreateDataFile(filePath string) error {
+	if _, err := os.Stat(filePath); err == nil {
+		return fmt.Errorf("%s already exists", filePath)
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	return Storage.WriteFileAtomic(filePath, func(w io.Writer) error {
+		return writeItems(w, nil)
+	})
+}
 
-	// Normalize row ID
-	rowIdNormed := rowId - 1
+// *archiveFilePath: Returns the path of the file that keeps the purged items.
+// *archiveFilePath: Gibt den Pfad der Datei zurück, welche die endgültig entfernten Artikel aufbewahrt.
+func archiveFilePath(dataFilePath string) string {
+	extension := filepath.Ext(dataFilePath)
+	return strings.TrimSuffix(dataFilePath, extension) + ".archive" + extension
+}
 
-	// Mark the item as deleted and set the deletion date
-	now := time.Now()
-	items[rowIdNormed].IsDeleted = true
-	items[rowIdNormed].DeleteDate = &now
+// *appendToArchive: Writes the existing archive followed by the passed items with their purge date.
+// *appendToArchive: Schreibt das bestehende Archiv gefolgt von den übergebenen Artikeln mit ihrem Entfernungsdatum.
+func appendToArchive(w io.Writer, archivePath string, purged []Item) error {
+	existing, err := os.ReadFile(archivePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if _, err := w.Write(existing); err != nil {
+		return err
+	}
 
-	// Update data in file
-	return updateDataInFile()
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+	// A new header is written whenever the columns changed since the last purge
+	header := append(append([]string{}, ItemColumns...), "PurgeDate")
+	headerLine := strings.Join(header, ";")
+	lastHeader := ""
+	for _, line := range strings.Split(string(existing), "\n") {
+		if strings.HasPrefix(line, ItemColumns[0]+";") {
+			lastHeader = strings.TrimSpace(line)
+		}
+	}
+	if lastHeader != headerLine {
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+	}
+	purgeDate := time.Now().Format(time.RFC3339)
+	for _, item := range purged {
+		if err := writer.Write(append(getItemAsStringSlice(item), purgeDate)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
 }
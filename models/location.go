@@ -0,0 +1,121 @@
+package models
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// NoLocation stands for the pieces in stock that have no location yet
+const NoLocation = ""
+
+// *LocationQuantity: Returns the pieces in stock at the location, NoLocation returns the pieces without location.
+// *LocationQuantity: Gibt die Stücke an Lager am Lagerort zurück, NoLocation gibt die Stücke ohne Lagerort zurück.
+func (item Item) LocationQuantity(location string) int {
+	if location == NoLocation {
+		return item.Unassigned()
+	}
+	return item.Locations[location]
+}
+
+// *Unassigned: Returns the pieces in stock that have no location.
+// *Unassigned: Gibt die Stücke an Lager zurück, die keinen Lagerort haben.
+func (item Item) Unassigned() int {
+	assigned := 0
+	for _, count := range item.Locations {
+		assigned += count
+	}
+	return max(item.Quantity-assigned, 0)
+}
+
+// *Transfer: Moves pieces in stock from one location to another, NoLocation on either side stands for the pieces
+// without location. The quantity stays the same.
+// *Transfer: Verschiebt Stücke an Lager von einem Lagerort zu einem anderen, NoLocation steht auf beiden Seiten für
+// die Stücke ohne Lagerort. Die Menge bleibt gleich.
+func (item *Item) Transfer(from, to string, count int) error {
+	if from == to {
+		return fmt.Errorf("the pieces are already at %s", LocationName(to))
+	}
+	if count < 1 || count > item.LocationQuantity(from) {
+		return fmt.Errorf("only %d pieces of %s are at %s", item.LocationQuantity(from), item.ArticleName, LocationName(from))
+	}
+	locations := maps.Clone(item.Locations)
+	if locations == nil {
+		locations = map[string]int{}
+	}
+	if from != NoLocation {
+		locations[from] -= count
+	}
+	if to != NoLocation {
+		locations[to] += count
+	}
+	item.Locations = withoutEmptyLocations(locations)
+	return nil
+}
+
+// *RenameLocation: Moves the pieces at the old location to the new name.
+// *RenameLocation: Verschiebt die Stücke am alten Lagerort zum neuen Namen.
+func (item *Item) RenameLocation(oldName, newName string) {
+	count, ok := item.Locations[oldName]
+	if !ok {
+		return
+	}
+	locations := maps.Clone(item.Locations)
+	delete(locations, oldName)
+	locations[newName] += count
+	item.Locations = locations
+}
+
+// *ItemsAtLocation: Returns the items that have pieces in stock at the location, deleted items included.
+// *ItemsAtLocation: Gibt die Artikel zurück, die Stücke an Lager am Lagerort haben, gelöschte eingeschlossen.
+func ItemsAtLocation(items []Item, location string) []Item {
+	var found []Item
+	for _, item := range items {
+		if item.LocationQuantity(location) > 0 {
+			found = append(found, item)
+		}
+	}
+	return found
+}
+
+// *fitLocations: Keeps the pieces at the locations within the quantity. When the quantity drops, the pieces without
+// location go first, then the pieces at the locations in alphabetical order.
+// *fitLocations: Hält die Stücke an den Lagerorten innerhalb der Menge. Sinkt die Menge, gehen zuerst die Stücke ohne
+// Lagerort weg, danach die Stücke an den Lagerorten in alphabetischer Reihenfolge.
+func (item *Item) fitLocations() {
+	excess := -item.Quantity
+	empty := false
+	for _, count := range item.Locations {
+		excess += count
+		empty = empty || count <= 0
+	}
+	if excess <= 0 && !empty {
+		return
+	}
+	locations := maps.Clone(item.Locations)
+	for _, location := range slices.Sorted(maps.Keys(locations)) {
+		taken := min(max(excess, 0), locations[location])
+		locations[location] -= taken
+		excess -= taken
+	}
+	item.Locations = withoutEmptyLocations(locations)
+}
+
+// *withoutEmptyLocations: Removes the locations without pieces, nil when none is left.
+// *withoutEmptyLocations: Entfernt die Lagerorte ohne Stücke, nil wenn keiner übrig bleibt.
+func withoutEmptyLocations(locations map[string]int) map[string]int {
+	maps.DeleteFunc(locations, func(_ string, count int) bool { return count <= 0 })
+	if len(locations) == 0 {
+		return nil
+	}
+	return locations
+}
+
+// *LocationName: Returns the name of the location for messages.
+// *LocationName: Gibt den Namen des Lagerorts für Meldungen zurück.
+func LocationName(location string) string {
+	if location == NoLocation {
+		return "no location"
+	}
+	return location
+}
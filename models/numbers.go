@@ -0,0 +1,210 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DuplicateArticleNumberError is returned when an item would get an article number another item already has
+type DuplicateArticleNumberError struct {
+	Number   string
+	Existing Item
+}
+
+func (e *DuplicateArticleNumberError) Error() string {
+	state := ""
+	if e.Existing.IsDeleted {
+		state = ", deleted"
+	}
+	return fmt.Sprintf("article number %s is already used by %s (ID %d%s)", e.Number, e.Existing.ArticleName, e.Existing.ID, state)
+}
+
+// *SameArticleNumber: Reports whether both article numbers are equal when case and surrounding spaces are ignored.
+// *SameArticleNumber: Gibt zurück, ob beide Artikelnummern ohne Beachtung von Gross-/Kleinschreibung und Leerzeichen gleich sind.
+func SameArticleNumber(first, second string) bool {
+	return strings.EqualFold(strings.TrimSpace(first), strings.TrimSpace(second))
+}
+
+// *FindArticleNumber: Returns the item other than excludeID that has the article number.
+// Deleted items only count when includeDeleted is set and an active item is preferred, items without article number
+// never conflict.
+// *FindArticleNumber: Gibt den Artikel ausser excludeID zurück, der die Artikelnummer hat.
+// Gelöschte Artikel zählen nur, wenn includeDeleted gesetzt ist, und ein aktiver Artikel wird bevorzugt, Artikel ohne
+// Artikelnummer stehen nie im Konflikt.
+func FindArticleNumber(items []Item, number string, excludeID int, includeDeleted bool) (Item, bool) {
+	if strings.TrimSpace(number) == "" {
+		return Item{}, false
+	}
+	var deleted *Item
+	for index, item := range items {
+		if item.ID == excludeID || (item.IsDeleted && !includeDeleted) || !SameArticleNumber(item.ArticleNumber, number) {
+			continue
+		}
+		if !item.IsDeleted {
+			return item, true
+		}
+		if deleted == nil {
+			deleted = &items[index]
+		}
+	}
+	if deleted != nil {
+		return *deleted, true
+	}
+	return Item{}, false
+}
+
+// *checkArticleNumber: Returns a DuplicateArticleNumberError when another item of the store has the number of the item.
+// *checkArticleNumber: Gibt einen DuplicateArticleNumberError zurück, wenn ein anderer Artikel des Speichers die Nummer des Artikels hat.
+func (s *MemoryItemStore) checkArticleNumber(item Item) error {
+	if existing, found := FindArticleNumber(s.items, item.ArticleNumber, item.ID, s.NumbersIncludeDeleted); found {
+		return &DuplicateArticleNumberError{Number: item.ArticleNumber, Existing: existing}
+	}
+	return nil
+}
+
+// NumberPattern is the form of the article numbers of a category, e.g. prefix "M" and width 3 give M001, M002 and so on.
+// With CheckDigit a Luhn check digit is appended to the running number, so typos in a number are found.
+type NumberPattern struct {
+	Prefix     string
+	Width      int
+	CheckDigit bool
+}
+
+// *IsSet: Reports whether the pattern defines anything, categories without a pattern accept any number.
+// *IsSet: Gibt zurück, ob das Muster etwas festlegt, Kategorien ohne Muster akzeptieren jede Nummer.
+func (p NumberPattern) IsSet() bool {
+	return p.Prefix != "" || p.Width > 0 || p.CheckDigit
+}
+
+// *Validate: Checks that numbers can be formed with the pattern.
+// *Validate: Prüft, ob mit dem Muster Nummern gebildet werden können.
+func (p NumberPattern) Validate() error {
+	if strings.ContainsFunc(p.Prefix, unicode.IsSpace) {
+		return fmt.Errorf("the prefix must not contain spaces")
+	}
+	if p.Prefix != "" && unicode.IsDigit(rune(p.Prefix[len(p.Prefix)-1])) {
+		return fmt.Errorf("the prefix must not end with a digit, the running number would be ambiguous")
+	}
+	if p.Width < 0 || p.Width > 9 {
+		return fmt.Errorf("the width must be between 0 and 9")
+	}
+	return nil
+}
+
+// *String: Describes the pattern, e.g. "M + 3 digits + check digit (M0018)".
+// *String: Beschreibt das Muster, z.B. "M + 3 digits + check digit (M0018)".
+func (p NumberPattern) String() string {
+	if !p.IsSet() {
+		return ""
+	}
+	parts := []string{}
+	if p.Prefix != "" {
+		parts = append(parts, p.Prefix)
+	}
+	if p.Width > 0 {
+		parts = append(parts, fmt.Sprintf("%d digits", p.Width))
+	} else {
+		parts = append(parts, "digits")
+	}
+	if p.CheckDigit {
+		parts = append(parts, "check digit")
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(parts, " + "), p.Format(1))
+}
+
+// *Format: Returns the article number with the passed running number.
+// *Format: Gibt die Artikelnummer mit der übergebenen laufenden Nummer zurück.
+func (p NumberPattern) Format(sequence int) string {
+	digits := fmt.Sprintf("%0*d", p.Width, sequence)
+	if p.CheckDigit {
+		digits += strconv.Itoa(luhnCheckDigit(digits))
+	}
+	return p.Prefix + digits
+}
+
+// *Check: Returns an error that tells the user how the number differs from the pattern.
+// *Check: Gibt einen Fehler zurück, der dem Benutzer sagt, wie die Nummer vom Muster abweicht.
+func (p NumberPattern) Check(number string) error {
+	number = strings.TrimSpace(number)
+	if len(number) < len(p.Prefix) || !strings.EqualFold(number[:len(p.Prefix)], p.Prefix) {
+		return fmt.Errorf("the number must start with %s", p.Prefix)
+	}
+	digits := number[len(p.Prefix):]
+	if digits == "" || strings.ContainsFunc(digits, func(char rune) bool { return char < '0' || char > '9' }) {
+		return fmt.Errorf("the number must be %s", p)
+	}
+	if p.CheckDigit {
+		if len(digits) < 2 {
+			return fmt.Errorf("the number must be %s", p)
+		}
+		if check := strconv.Itoa(luhnCheckDigit(digits[:len(digits)-1])); digits[len(digits)-1:] != check {
+			return fmt.Errorf("the check digit of %s is wrong, it would be %s", number, check)
+		}
+		digits = digits[:len(digits)-1]
+	}
+	if p.Width > 0 && len(digits) != p.Width {
+		return fmt.Errorf("the number must be %s", p)
+	}
+	return nil
+}
+
+// *Next: Returns the number after the highest number of the pattern in use. Deleted items are included, so their
+// numbers are never given again, and so are numbers from before a check digit was added to the pattern.
+// *Next: Gibt die Nummer nach der höchsten verwendeten Nummer des Musters zurück. Gelöschte Artikel zählen mit, damit
+// ihre Nummern nie erneut vergeben werden, ebenso Nummern aus der Zeit, bevor das Muster eine Prüfziffer hatte.
+func (p NumberPattern) Next(items []Item) (string, error) {
+	withoutCheckDigit := p
+	withoutCheckDigit.CheckDigit = false
+	highest := 0
+	for _, item := range items {
+		// Only numbers that don't have a valid check digit are read without one, otherwise the check digit of a
+		// pattern without a fixed width would be taken as part of the running number
+		sequence, ok := p.sequence(item.ArticleNumber)
+		if !ok && p.CheckDigit {
+			sequence, ok = withoutCheckDigit.sequence(item.ArticleNumber)
+		}
+		if ok && sequence > highest {
+			highest = sequence
+		}
+	}
+	next := p.Format(highest + 1)
+	if p.Check(next) != nil {
+		return "", fmt.Errorf("all %d numbers of the pattern %s are used", highest, p)
+	}
+	return next, nil
+}
+
+// *sequence: Returns the running number of a number that matches the pattern.
+// *sequence: Gibt die laufende Nummer einer Nummer zurück, die dem Muster entspricht.
+func (p NumberPattern) sequence(number string) (int, bool) {
+	if p.Check(number) != nil {
+		return 0, false
+	}
+	digits := strings.TrimSpace(number)[len(p.Prefix):]
+	if p.CheckDigit {
+		digits = digits[:len(digits)-1]
+	}
+	sequence, err := strconv.Atoi(digits)
+	return sequence, err == nil
+}
+
+// *luhnCheckDigit: Returns the digit that makes the passed digits valid in the Luhn algorithm.
+// *luhnCheckDigit: Gibt die Ziffer zurück, welche die übergebenen Ziffern im Luhn-Algorithmus gültig macht.
+func luhnCheckDigit(digits string) int {
+	sum := 0
+	double := true
+	for index := len(digits) - 1; index >= 0; index-- {
+		digit := int(digits[index] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
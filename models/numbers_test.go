@@ -0,0 +1,80 @@
+package models
+
+import "testing"
+
+func TestNumberPatternFormat(t *testing.T) {
+	tests := []struct {
+		pattern  NumberPattern
+		sequence int
+		want     string
+	}{
+		{NumberPattern{Prefix: "M", Width: 3}, 1, "M001"},
+		{NumberPattern{Prefix: "M", Width: 3}, 1234, "M1234"},
+		{NumberPattern{Prefix: "M", Width: 3, CheckDigit: true}, 1, "M0018"},
+		{NumberPattern{Prefix: "M", CheckDigit: true}, 1, "M18"},
+		{NumberPattern{Prefix: "M", CheckDigit: true}, 2, "M26"},
+		{NumberPattern{Width: 2}, 7, "07"},
+	}
+	for _, test := range tests {
+		if got := test.pattern.Format(test.sequence); got != test.want {
+			t.Errorf("%+v.Format(%d) = %q, want %q", test.pattern, test.sequence, got, test.want)
+		}
+	}
+}
+
+func TestNumberPatternCheck(t *testing.T) {
+	tests := []struct {
+		pattern NumberPattern
+		number  string
+		valid   bool
+	}{
+		{NumberPattern{Prefix: "M", Width: 3}, "M001", true},
+		{NumberPattern{Prefix: "M", Width: 3}, "m001", true},
+		{NumberPattern{Prefix: "M", Width: 3}, "M01", false},
+		{NumberPattern{Prefix: "M", Width: 3}, "L001", false},
+		{NumberPattern{Prefix: "M", Width: 3}, "M00A", false},
+		{NumberPattern{Prefix: "M", Width: 3, CheckDigit: true}, "M0018", true},
+		{NumberPattern{Prefix: "M", Width: 3, CheckDigit: true}, "M0013", false},
+		{NumberPattern{Prefix: "M", Width: 3, CheckDigit: true}, "M001", false},
+		{NumberPattern{Prefix: "M", CheckDigit: true}, "M18", true},
+		{NumberPattern{Prefix: "M", CheckDigit: true}, "M1", false},
+		{NumberPattern{Prefix: "M", CheckDigit: true}, "M19", false},
+	}
+	for _, test := range tests {
+		if err := test.pattern.Check(test.number); (err == nil) != test.valid {
+			t.Errorf("%+v.Check(%q) = %v, want valid %v", test.pattern, test.number, err, test.valid)
+		}
+	}
+}
+
+func TestNumberPatternNext(t *testing.T) {
+	items := func(numbers ...string) []Item {
+		var list []Item
+		for index, number := range numbers {
+			list = append(list, Item{ID: index + 1, ArticleNumber: number})
+		}
+		return list
+	}
+	tests := []struct {
+		name    string
+		pattern NumberPattern
+		items   []Item
+		want    string
+		wantErr bool
+	}{
+		{"empty inventory", NumberPattern{Prefix: "M", Width: 3}, nil, "M001", false},
+		{"after the highest", NumberPattern{Prefix: "M", Width: 3}, items("M002", "M001", "X999"), "M003", false},
+		{"deleted items count", NumberPattern{Prefix: "M", Width: 3}, []Item{{ID: 1, ArticleNumber: "M005", IsDeleted: true}}, "M006", false},
+		{"check digit", NumberPattern{Prefix: "M", Width: 3, CheckDigit: true}, items("M0018"), "M0026", false},
+		{"numbers from before the check digit", NumberPattern{Prefix: "M", Width: 3, CheckDigit: true}, items("M001", "M002"), "M0034", false},
+		{"check digit without width", NumberPattern{Prefix: "M", CheckDigit: true}, items("M18"), "M26", false},
+		{"check digit without width, several numbers", NumberPattern{Prefix: "M", CheckDigit: true}, items("M18", "M26"), "M34", false},
+		{"all numbers used", NumberPattern{Prefix: "M", Width: 1}, items("M9"), "", true},
+	}
+	for _, test := range tests {
+		got, err := test.pattern.Next(test.items)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("%s: Next() = %q, %v, want %q, error %v", test.name, got, err, test.want, test.wantErr)
+		}
+	}
+}
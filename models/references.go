@@ -0,0 +1,74 @@
+package models
+
+import (
+	"it_inventar/models/Audit"
+	"slices"
+)
+
+// ReferenceIssue is an item that refers to a category or supplier which is not in the list.
+// Kind is Audit.EntityCategory or Audit.EntitySupplier.
+type ReferenceIssue struct {
+	ItemID int
+	Kind   string
+	Name   string
+}
+
+// *Reference: Returns the category or the supplier of the item.
+// *Reference: Gibt die Kategorie oder den Lieferanten des Artikels zurück.
+func (item Item) Reference(kind string) string {
+	if kind == Audit.EntityCategory {
+		return item.Category
+	}
+	return item.Supplier
+}
+
+// *SetReference: Changes the category or the supplier of the item.
+// *SetReference: Ändert die Kategorie oder den Lieferanten des Artikels.
+func (item *Item) SetReference(kind, name string) {
+	if kind == Audit.EntityCategory {
+		item.Category = name
+	} else {
+		item.Supplier = name
+	}
+}
+
+// *ItemsReferencing: Returns the active items whose category or supplier is the passed name.
+// Deleted items keep the names they had, they are checked on restore.
+// *ItemsReferencing: Gibt die aktiven Artikel zurück, deren Kategorie oder Lieferant der übergebene Name ist.
+// Gelöschte Artikel behalten ihre bisherigen Namen, sie werden beim Wiederherstellen geprüft.
+func ItemsReferencing(items []Item, kind, name string) []Item {
+	return slices.DeleteFunc(AllItemsReferencing(items, kind, name), func(item Item) bool { return item.IsDeleted })
+}
+
+// *AllItemsReferencing: Returns the items whose category or supplier is the passed name, deleted items included.
+// *AllItemsReferencing: Gibt die Artikel zurück, deren Kategorie oder Lieferant der übergebene Name ist, gelöschte eingeschlossen.
+func AllItemsReferencing(items []Item, kind, name string) []Item {
+	var referencing []Item
+	for _, item := range items {
+		if item.Reference(kind) == name {
+			referencing = append(referencing, item)
+		}
+	}
+	return referencing
+}
+
+// *CheckReferences: Returns every category and supplier of the active items that is missing in the lists.
+// Deleted items keep the names they had, e.g. of a category deleted together with them, they are checked on restore.
+// *CheckReferences: Gibt jede Kategorie und jeden Lieferanten der aktiven Artikel zurück, die in den Listen fehlen.
+// Gelöschte Artikel behalten ihre bisherigen Namen, z.B. einer mit ihnen gelöschten Kategorie, sie werden beim
+// Wiederherstellen geprüft.
+func CheckReferences(items []Item, categories, suppliers []string) []ReferenceIssue {
+	var issues []ReferenceIssue
+	for _, item := range items {
+		if item.IsDeleted {
+			continue
+		}
+		if !slices.Contains(categories, item.Category) {
+			issues = append(issues, ReferenceIssue{ItemID: item.ID, Kind: Audit.EntityCategory, Name: item.Category})
+		}
+		if !slices.Contains(suppliers, item.Supplier) {
+			issues = append(issues, ReferenceIssue{ItemID: item.ID, Kind: Audit.EntitySupplier, Name: item.Supplier})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,355 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CurrentSchemaVersion is the version of the data file format written by this program.
+// Version 0: 8 columns without header, version 1: ID column added, version 2: version line and header row,
+// version 3: Attributes column added, version 4: Assets column added, version 5: CheckedOut column added,
+// version 6: Locations column added, version 7: MinimumQuantity and ReorderQuantity columns added.
+const CurrentSchemaVersion = 7
+
+// schemaVersionPrefix starts the first line of a data file and is followed by the schema version
+const schemaVersionPrefix = "#schema_version="
+
+// ItemColumns are the column names of the header row, in the order they are written.
+var ItemColumns = []string{
+	"ID",
+	"ArticleName",
+	"Category",
+	"ArticleNumber",
+	"Supplier",
+	"Quantity",
+	"Note",
+	"DeleteDate",
+	"IsDeleted",
+	"Attributes",
+	"Assets",
+	"CheckedOut",
+	"Locations",
+	"MinimumQuantity",
+	"ReorderQuantity",
+}
+
+// version1Columns are the columns of a data file in schema version 1, version 0 lacks the ID
+var version1Columns = []string{"ID", "ArticleName", "Category", "ArticleNumber", "Supplier", "Quantity", "Note", "DeleteDate", "IsDeleted"}
+
+// dataTable is a data file as read from disk, before the rows are parsed into items.
+type dataTable struct {
+	version int
+	header  []string
+	rows    []dataRow
+	// issues are the rows that could not be read, they are left out of rows
+	issues []RowIssue
+}
+
+// dataRow is a single record with the line it was read from.
+type dataRow struct {
+	line   int
+	raw    string
+	fields []string
+}
+
+// RowIssue is a row of the data file that could not be loaded.
+type RowIssue struct {
+	Line    int
+	Content string
+	Reason  string
+}
+
+// quarantine moves the passed row into the issues of the table.
+func (t *dataTable) quarantine(row dataRow, reason string) {
+	t.issues = append(t.issues, RowIssue{Line: row.line, Content: row.raw, Reason: reason})
+}
+
+// migration upgrades a data table by exactly one schema version.
+type migration struct {
+	description string
+	migrate     func(table *dataTable) error
+}
+
+// migrations holds the upgrade from version i to version i+1 at index i.
+var migrations = []migration{
+	{description: "add the ID column", migrate: migrateAddIdColumn},
+	{description: "add the version line and header row", migrate: migrateAddHeader},
+	{description: "add the Attributes column", migrate: migrateAddAttributesColumn},
+	{description: "add the Assets column", migrate: migrateAddAssetsColumn},
+	{description: "add the CheckedOut column", migrate: migrateAddCheckedOutColumn},
+	{description: "add the Locations column", migrate: migrateAddLocationsColumn},
+	{description: "add the MinimumQuantity and ReorderQuantity columns", migrate: migrateAddStockLevelColumns},
+}
+
+// *readDataTable: Reads a data file record by record and detects its schema version.
+// Records that are no valid CSV are collected as issues instead of failing the whole file.
+// *readDataTable: Liest eine Datendatei Datensatz für Datensatz und erkennt ihre Schema-Version.
+// Datensätze, die kein gültiges CSV sind, werden als Probleme gesammelt, statt die ganze Datei abzulehnen.
+func readDataTable(reader io.Reader) (*dataTable, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	table := &dataTable{version: CurrentSchemaVersion, header: ItemColumns}
+
+	// The version line is no CSV record, the records start in the line after it
+	text := strings.TrimLeft(string(content), "\r\n")
+	firstLine := 1 + strings.Count(string(content[:len(content)-len(text)]), "\n")
+	versioned := strings.HasPrefix(text, schemaVersionPrefix)
+	if versioned {
+		versionLine, rest, _ := strings.Cut(text, "\n")
+		versionLine = strings.TrimSpace(versionLine)
+		version, err := strconv.Atoi(strings.TrimPrefix(versionLine, schemaVersionPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema version line %q", versionLine)
+		}
+		if version > CurrentSchemaVersion {
+			return nil, fmt.Errorf("the data file has schema version %d, this program only supports up to version %d", version, CurrentSchemaVersion)
+		}
+		table.version = version
+		text = rest
+		firstLine++
+	}
+	table.readRows(text, firstLine)
+
+	if versioned {
+		if len(table.rows) == 0 || table.rows[0].line != firstLine {
+			return nil, fmt.Errorf("the data file has no valid header row")
+		}
+		table.header = table.rows[0].fields
+		table.rows = table.rows[1:]
+		return table, nil
+	}
+	if len(table.rows) == 0 {
+		return table, nil
+	}
+
+	// Files without version line are told apart by the number of columns of their first well-formed row
+	table.header = nil
+	for _, row := range table.rows {
+		switch len(row.fields) {
+		case len(version1Columns) - 1:
+			table.version = 0
+			return table, nil
+		case len(version1Columns):
+			table.version = 1
+			return table, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown data file format, no row has %d or %d columns", len(version1Columns)-1, len(version1Columns))
+}
+
+// *readRows: Reads the CSV records of text, which starts at line firstLine of the file.
+// A single reader is used for all records, so quoted fields may contain line breaks.
+// *readRows: Liest die CSV-Datensätze von text, das in Zeile firstLine der Datei beginnt.
+// Ein einziger Reader liest alle Datensätze, daher dürfen Felder in Anführungszeichen Zeilenumbrüche enthalten.
+func (t *dataTable) readRows(text string, firstLine int) {
+	csvReader := csv.NewReader(strings.NewReader(text))
+	csvReader.Comma = ';'
+	csvReader.FieldsPerRecord = -1
+	for {
+		start := csvReader.InputOffset()
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return
+		}
+		row := dataRow{raw: strings.Trim(text[start:csvReader.InputOffset()], "\r\n")}
+		if err != nil {
+			// The line is reported with the issue, only the cause is kept as reason
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				row.line = firstLine + parseErr.StartLine - 1
+				err = parseErr.Err
+			}
+			t.quarantine(row, err.Error())
+			continue
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		line, _ := csvReader.FieldPos(0)
+		row.line = firstLine + line - 1
+		row.fields = record
+		t.rows = append(t.rows, row)
+	}
+}
+
+// *migrateTable: Upgrades the table step by step to the current schema version.
+// *migrateTable: Aktualisiert die Tabelle Schritt für Schritt auf die aktuelle Schema-Version.
+func migrateTable(table *dataTable) error {
+	for table.version < CurrentSchemaVersion {
+		step := migrations[table.version]
+		if err := step.migrate(table); err != nil {
+			return fmt.Errorf("migration to schema version %d (%s) failed: %v", table.version+1, step.description, err)
+		}
+		table.version++
+	}
+	return nil
+}
+
+// *migrateAddIdColumn: Version 0 to 1, prepends an empty ID, the store assigns the IDs after loading.
+// *migrateAddIdColumn: Version 0 zu 1, stellt eine leere ID voran, der Speicher vergibt die IDs nach dem Laden.
+func migrateAddIdColumn(table *dataTable) error {
+	var migratedRows []dataRow
+	for _, row := range table.rows {
+		if len(row.fields) != len(version1Columns)-1 {
+			table.quarantine(row, fmt.Sprintf("row has %d columns, expected %d", len(row.fields), len(version1Columns)-1))
+			continue
+		}
+		row.fields = append([]string{""}, row.fields...)
+		migratedRows = append(migratedRows, row)
+	}
+	table.rows = migratedRows
+	return nil
+}
+
+// *migrateAddHeader: Version 1 to 2, the columns get the names they had in version 1.
+// *migrateAddHeader: Version 1 zu 2, die Spalten erhalten die Namen, die sie in Version 1 hatten.
+func migrateAddHeader(table *dataTable) error {
+	table.header = version1Columns
+	return nil
+}
+
+// *migrateAddAttributesColumn: Version 2 to 3, appends the Attributes column, existing items have no attributes.
+// *migrateAddAttributesColumn: Version 2 zu 3, hängt die Spalte Attributes an, bestehende Artikel haben keine Attribute.
+func migrateAddAttributesColumn(table *dataTable) error {
+	table.header = append(slices.Clone(table.header), "Attributes")
+	for index := range table.rows {
+		table.rows[index].fields = append(table.rows[index].fields, "")
+	}
+	return nil
+}
+
+// *migrateAddAssetsColumn: Version 3 to 4, appends the Assets column, existing items have no assets.
+// *migrateAddAssetsColumn: Version 3 zu 4, hängt die Spalte Assets an, bestehende Artikel haben keine Geräte.
+func migrateAddAssetsColumn(table *dataTable) error {
+	table.header = append(slices.Clone(table.header), "Assets")
+	for index := range table.rows {
+		table.rows[index].fields = append(table.rows[index].fields, "")
+	}
+	return nil
+}
+
+// *migrateAddCheckedOutColumn: Version 4 to 5, appends the CheckedOut column, no pieces are checked out yet.
+// *migrateAddCheckedOutColumn: Version 4 zu 5, hängt die Spalte CheckedOut an, es sind noch keine Stücke ausgegeben.
+func migrateAddCheckedOutColumn(table *dataTable) error {
+	table.header = append(slices.Clone(table.header), "CheckedOut")
+	for index := range table.rows {
+		table.rows[index].fields = append(table.rows[index].fields, "")
+	}
+	return nil
+}
+
+// *migrateAddLocationsColumn: Version 5 to 6, appends the Locations column, the stock has no location yet.
+// *migrateAddLocationsColumn: Version 5 zu 6, hängt die Spalte Locations an, der Bestand hat noch keinen Lagerort.
+func migrateAddLocationsColumn(table *dataTable) error {
+	table.header = append(slices.Clone(table.header), "Locations")
+	for index := range table.rows {
+		table.rows[index].fields = append(table.rows[index].fields, "")
+	}
+	return nil
+}
+
+// *migrateAddStockLevelColumns: Version 6 to 7, appends the MinimumQuantity and ReorderQuantity columns, existing items
+// use the stock level of their category.
+// *migrateAddStockLevelColumns: Version 6 zu 7, hängt die Spalten MinimumQuantity und ReorderQuantity an, bestehende
+// Artikel verwenden den Mindestbestand ihrer Kategorie.
+func migrateAddStockLevelColumns(table *dataTable) error {
+	table.header = append(slices.Clone(table.header), "MinimumQuantity", "ReorderQuantity")
+	for index := range table.rows {
+		table.rows[index].fields = append(table.rows[index].fields, "", "")
+	}
+	return nil
+}
+
+// *columnIndexes: Maps every column name of the header to its position.
+// *columnIndexes: Ordnet jedem Spaltennamen der Kopfzeile seine Position zu.
+func columnIndexes(header []string) map[string]int {
+	columns := make(map[string]int, len(header))
+	for index, name := range header {
+		columns[strings.TrimSpace(name)] = index
+	}
+	return columns
+}
+
+// *writeSchemaHeader: Writes the version line and the header row of the current schema.
+// *writeSchemaHeader: Schreibt die Versionszeile und die Kopfzeile des aktuellen Schemas.
+func writeSchemaHeader(writer *csv.Writer) error {
+	if err := writer.Write([]string{schemaVersionPrefix + IntToString(CurrentSchemaVersion)}); err != nil {
+		return err
+	}
+	return writer.Write(ItemColumns)
+}
+
+// *backupBeforeMigration: Copies the data file before it is overwritten in a newer schema version.
+// *backupBeforeMigration: Kopiert die Datendatei, bevor sie in einer neueren Schema-Version überschrieben wird.
+func backupBeforeMigration(filePath string, version int) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	backupPath := fmt.Sprintf("%s.v%d.bak", filePath, version)
+	for number := 1; ; number++ {
+		if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+			break
+		}
+		backupPath = fmt.Sprintf("%s.v%d.%d.bak", filePath, version, number)
+	}
+	return backupPath, os.WriteFile(backupPath, content, 0644)
+}
+
+// *quarantineFilePath: Returns the path of the file that collects the rows which could not be loaded.
+// *quarantineFilePath: Gibt den Pfad der Datei zurück, welche die nicht ladbaren Zeilen sammelt.
+func quarantineFilePath(dataFilePath string) string {
+	return dataFilePath + ".quarantine"
+}
+
+// *writeQuarantine: Appends the passed issues to the quarantine file, rows that are already in there are skipped.
+// *writeQuarantine: Hängt die übergebenen Probleme an die Quarantänedatei an, bereits enthaltene Zeilen werden übersprungen.
+func writeQuarantine(quarantinePath string, issues []RowIssue) error {
+	known := map[string]bool{}
+	if existing, err := os.Open(quarantinePath); err == nil {
+		csvReader := csv.NewReader(existing)
+		csvReader.Comma = ';'
+		csvReader.FieldsPerRecord = -1
+		records, _ := csvReader.ReadAll()
+		_ = existing.Close()
+		for _, record := range records {
+			if len(record) == 4 {
+				known[record[3]] = true
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	file, err := os.OpenFile(quarantinePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Comma = ';'
+	now := time.Now().Format(time.RFC3339)
+	for _, issue := range issues {
+		if known[issue.Content] {
+			continue
+		}
+		if err := writer.Write([]string{now, IntToString(issue.Line), issue.Reason, issue.Content}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
@@ -0,0 +1,210 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMultiLineNoteRoundTrip(t *testing.T) {
+	items := []Item{
+		{ID: 1, ArticleName: "Monitor", Category: "Monitore", ArticleNumber: "M001", Supplier: "Dell", Quantity: 3, Note: "first line\nsecond line; with separator\n\"quoted\""},
+		{ID: 2, ArticleName: "Maus", Category: "Zubehör", ArticleNumber: "Z001", Supplier: "Logitech", Quantity: 10, Note: strings.Repeat("long note ", 10000) + "end"},
+	}
+	var buffer bytes.Buffer
+	if err := writeItems(&buffer, items); err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	readItems, version, issues, err := readItemsFromFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != CurrentSchemaVersion || len(issues) != 0 {
+		t.Fatalf("read version %d with issues %v, want version %d without issues", version, issues, CurrentSchemaVersion)
+	}
+	if len(readItems) != len(items) {
+		t.Fatalf("read %d items, want %d", len(readItems), len(items))
+	}
+	for index, item := range readItems {
+		if item.ID != items[index].ID || item.Note != items[index].Note {
+			t.Errorf("item %d read as ID %d with a note of %d bytes, want ID %d with the written note of %d bytes", index, item.ID, len(item.Note), items[index].ID, len(items[index].Note))
+		}
+	}
+}
+
+func TestReadDataTableReportsLinesAfterMultiLineFields(t *testing.T) {
+	content := "#schema_version=2\n" +
+		"ID;ArticleName;Category;ArticleNumber;Supplier;Quantity;Note;DeleteDate;IsDeleted\n" +
+		"1;Monitor;Monitore;M001;Dell;3;\"two\nlines\";;false\n" +
+		"2;Maus;Zubehör;Z\"001;Logitech;10;;;false\n" +
+		"3;Tastatur;Zubehör;Z002;Logitech;5;;;false\n"
+	table, err := readDataTable(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.rows) != 2 || table.rows[0].line != 3 || table.rows[1].line != 6 {
+		t.Fatalf("read rows %+v, want the rows of lines 3 and 6", table.rows)
+	}
+	if len(table.issues) != 1 || table.issues[0].Line != 5 || !strings.HasPrefix(table.issues[0].Content, "2;Maus") {
+		t.Fatalf("read issues %+v, want the row of line 5", table.issues)
+	}
+}
+
+// copyFixture copies a file of testdata into a new directory as data.csv and returns its path and content
+func copyFixture(t *testing.T, name string) (string, []byte) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(t.TempDir(), FileData)
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath, content
+}
+
+func TestMigrateOlderFormats(t *testing.T) {
+	for version := 0; version < CurrentSchemaVersion; version++ {
+		t.Run(fmt.Sprintf("version %d", version), func(t *testing.T) {
+			filePath, original := copyFixture(t, fmt.Sprintf("data_v%d.csv", version))
+			store := NewCsvItemStore(filePath)
+			if err := store.Initialize(); err != nil {
+				t.Fatal(err)
+			}
+
+			items := store.GetAllItems()
+			if len(items) != 2 {
+				t.Fatalf("read %d items, want 2", len(items))
+			}
+			monitor, mouse := items[0], items[1]
+			if monitor.ID != 1 || monitor.ArticleName != "Monitor" || monitor.Quantity != 3 || monitor.Note != "27 Zoll" || monitor.IsDeleted {
+				t.Errorf("first item read as %+v", monitor)
+			}
+			if mouse.ID != 2 || mouse.Category != "Zubehör" || mouse.Quantity != 10 || !mouse.IsDeleted || mouse.DeleteDate == nil {
+				t.Errorf("second item read as %+v", mouse)
+			}
+			if version >= 3 && monitor.Attributes["Diagonal"] != "27" {
+				t.Errorf("attributes of the first item read as %v", monitor.Attributes)
+			}
+
+			// The file is written in the current version, the original is kept as backup
+			migrated, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(migrated), fmt.Sprintf("%s%d\n%s\n", schemaVersionPrefix, CurrentSchemaVersion, strings.Join(ItemColumns, ";"))) {
+				t.Errorf("migrated file starts with %q", strings.SplitN(string(migrated), "\n", 3)[:2])
+			}
+			backup, err := os.ReadFile(fmt.Sprintf("%s.v%d.bak", filePath, version))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(backup, original) {
+				t.Errorf("backup contains %q, want the original file", backup)
+			}
+
+			// Reading the migrated file again gives the same items
+			reloaded := NewCsvItemStore(filePath)
+			if err := reloaded.Initialize(); err != nil {
+				t.Fatal(err)
+			}
+			for index, item := range reloaded.GetAllItems() {
+				if !item.Equal(items[index]) {
+					t.Errorf("item %d reloaded as %+v, want %+v", index, item, items[index])
+				}
+			}
+		})
+	}
+}
+
+func TestMalformedRowsBlockSavingUntilAcknowledged(t *testing.T) {
+	filePath, original := copyFixture(t, "data_malformed.csv")
+	store := NewCsvItemStore(filePath)
+	if err := store.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	report := store.LoadReport()
+	if report.Acknowledged {
+		t.Error("the load report with issues is acknowledged")
+	}
+	var lines []int
+	for _, issue := range report.Issues {
+		lines = append(lines, issue.Line)
+	}
+	if fmt.Sprint(lines) != "[5 4]" {
+		t.Errorf("issues reported in lines %v, want the CSV error of line 5 and the short row of line 4", lines)
+	}
+	if len(store.GetAllItems()) != 2 {
+		t.Errorf("read %d items, want the 2 valid rows", len(store.GetAllItems()))
+	}
+
+	quarantine, err := os.ReadFile(report.QuarantineFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(quarantine), "too few columns") || !strings.Contains(string(quarantine), "Tastatur") {
+		t.Errorf("quarantine file is missing the rows:\n%s", quarantine)
+	}
+
+	// Saving now would remove the rows from the data file
+	item := store.GetAllItems()[0]
+	item.Quantity++
+	if err := store.UpdateItem(item.ID, item); !errors.Is(err, ErrSaveBlocked) {
+		t.Fatalf("saving before the acknowledgement returned %v, want %v", err, ErrSaveBlocked)
+	}
+	if content, _ := os.ReadFile(filePath); !bytes.Equal(content, original) {
+		t.Fatal("the data file was changed before the report was acknowledged")
+	}
+
+	// Loading the same rows again keeps the acknowledgement and doesn't repeat them in the quarantine file
+	store.AcknowledgeLoadReport()
+	if err := store.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if !store.LoadReport().Acknowledged {
+		t.Error("the acknowledgement was lost when the same rows were loaded again")
+	}
+	if again, _ := os.ReadFile(report.QuarantineFile); !bytes.Equal(again, quarantine) {
+		t.Errorf("the quarantine file changed when the same rows were loaded again:\n%s", again)
+	}
+
+	if err := store.UpdateItem(item.ID, item); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "Tastatur") || strings.Contains(string(content), "too few columns") {
+		t.Error("the quarantined rows are still in the data file after saving")
+	}
+}
+
+func TestMigrationWaitsForAcknowledgedReport(t *testing.T) {
+	filePath, original := copyFixture(t, "data_v1.csv")
+	content := append(bytes.Clone(original), "3;Tastatur;Zubehör\n"...)
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := NewCsvItemStore(filePath)
+	if err := store.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.ReadFile(filePath); !bytes.Equal(after, content) {
+		t.Error("the older file was migrated although rows could not be loaded")
+	}
+	if _, err := os.Stat(filePath + ".v1.bak"); !os.IsNotExist(err) {
+		t.Error("a migration backup was written although the file was not migrated")
+	}
+}
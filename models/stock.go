@@ -0,0 +1,92 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StockLevel is the minimum quantity of an item and the quantity that is reordered when the stock falls below it.
+// Items without a level of their own use the one of their category. Defined marks a level of its own, so an explicit
+// minimum of 0 turns the inherited minimum off instead of falling back to it.
+type StockLevel struct {
+	Minimum int
+	Reorder int
+	Defined bool
+}
+
+// *ParseStockLevel: Parses the minimum and reorder columns of a file, an empty minimum leaves the level undefined.
+// *ParseStockLevel: Liest die Spalten Mindestbestand und Bestellmenge einer Datei, ein leerer Mindestbestand lässt den Bestand undefiniert.
+func ParseStockLevel(minimum, reorder string) StockLevel {
+	if strings.TrimSpace(minimum) == "" {
+		return StockLevel{}
+	}
+	return StockLevel{Minimum: StringToInt(minimum), Reorder: StringToInt(reorder), Defined: true}
+}
+
+// *IsDefined: Reports whether the level is an own one, also with a minimum of 0, and replaces the inherited level.
+// *IsDefined: Gibt zurück, ob es ein eigener Bestand ist, auch mit Mindestbestand 0, der den geerbten ersetzt.
+func (l StockLevel) IsDefined() bool {
+	return l.Defined || l.Minimum > 0
+}
+
+// *IsSet: Reports whether the level has a minimum, the stock of items without one is not watched.
+// *IsSet: Gibt zurück, ob der Bestand einen Mindestbestand hat, der Bestand von Artikeln ohne wird nicht überwacht.
+func (l StockLevel) IsSet() bool {
+	return l.Minimum > 0
+}
+
+// *Validate: Checks that the quantities are not negative and that a reorder quantity comes with a minimum.
+// *Validate: Prüft, dass die Mengen nicht negativ sind und eine Bestellmenge nur mit einem Mindestbestand angegeben wird.
+func (l StockLevel) Validate() error {
+	if l.Minimum < 0 || l.Reorder < 0 {
+		return fmt.Errorf("the quantities must not be negative")
+	}
+	if l.Reorder > 0 && l.Minimum == 0 {
+		return fmt.Errorf("a reorder quantity needs a minimum quantity")
+	}
+	return nil
+}
+
+// *String: Describes the level, e.g. "minimum 5, reorder 10", "no minimum" for an own minimum of 0 and empty when
+// the level is not defined.
+// *String: Beschreibt den Bestand, z.B. "minimum 5, reorder 10", "no minimum" bei eigenem Mindestbestand 0 und leer,
+// wenn der Bestand nicht definiert ist.
+func (l StockLevel) String() string {
+	if !l.IsDefined() {
+		return ""
+	}
+	if !l.IsSet() {
+		return "no minimum"
+	}
+	if l.Reorder == 0 {
+		return fmt.Sprintf("minimum %d", l.Minimum)
+	}
+	return fmt.Sprintf("minimum %d, reorder %d", l.Minimum, l.Reorder)
+}
+
+// *IsBelow: Reports whether the stock of the item is below the minimum of the level.
+// *IsBelow: Gibt zurück, ob der Bestand des Artikels unter dem Mindestbestand liegt.
+func (item Item) IsBelow(level StockLevel) bool {
+	return level.IsSet() && item.Quantity < level.Minimum
+}
+
+// *OrderQuantity: Returns the pieces to order for the item, the reorder quantity or else the pieces missing to the minimum.
+// *OrderQuantity: Gibt die zu bestellenden Stücke zurück, die Bestellmenge oder sonst die bis zum Mindestbestand fehlenden Stücke.
+func (l StockLevel) OrderQuantity(item Item) int {
+	if l.Reorder > 0 {
+		return l.Reorder
+	}
+	return max(l.Minimum-item.Quantity, 0)
+}
+
+// *ItemsBelowMinimum: Returns the active items whose stock is below the minimum returned by levelOf.
+// *ItemsBelowMinimum: Gibt die aktiven Artikel zurück, deren Bestand unter dem von levelOf gelieferten Mindestbestand liegt.
+func ItemsBelowMinimum(items []Item, levelOf func(item Item) StockLevel) []Item {
+	var below []Item
+	for _, item := range items {
+		if !item.IsDeleted && item.IsBelow(levelOf(item)) {
+			below = append(below, item)
+		}
+	}
+	return below
+}
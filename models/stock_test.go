@@ -0,0 +1,54 @@
+package models
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStockLevelRoundTrip(t *testing.T) {
+	items := []Item{
+		{ID: 1, ArticleName: "Monitor", Category: "Monitore", ArticleNumber: "M001", Supplier: "Dell", Quantity: 3},
+		{ID: 2, ArticleName: "Toner", Category: "Toner", ArticleNumber: "T001", Supplier: "HP", Quantity: 1, StockLevel: StockLevel{Defined: true}},
+		{ID: 3, ArticleName: "Maus", Category: "Zubehör", ArticleNumber: "Z001", Supplier: "Logitech", Quantity: 10, StockLevel: StockLevel{Minimum: 5, Reorder: 20, Defined: true}},
+	}
+	var buffer bytes.Buffer
+	if err := writeItems(&buffer, items); err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(filePath, buffer.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	readItems, _, _, err := readItemsFromFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readItems) != len(items) {
+		t.Fatalf("read %d items, want %d", len(readItems), len(items))
+	}
+	for index, item := range readItems {
+		if item.StockLevel != items[index].StockLevel {
+			t.Errorf("item %d read with stock level %+v, want %+v", item.ID, item.StockLevel, items[index].StockLevel)
+		}
+	}
+}
+
+func TestStockLevelString(t *testing.T) {
+	tests := []struct {
+		level StockLevel
+		want  string
+	}{
+		{StockLevel{}, ""},
+		{StockLevel{Defined: true}, "no minimum"},
+		{StockLevel{Minimum: 5, Defined: true}, "minimum 5"},
+		{StockLevel{Minimum: 5, Reorder: 20, Defined: true}, "minimum 5, reorder 20"},
+	}
+	for _, test := range tests {
+		if got := test.level.String(); got != test.want {
+			t.Errorf("%+v.String() = %q, want %q", test.level, got, test.want)
+		}
+	}
+}
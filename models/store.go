@@ -0,0 +1,651 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Storage"
+	"path/filepath"
+	"time"
+)
+
+// ItemStore is the storage backend of the inventory. The controllers only work against this interface,
+// so the same handlers can run on top of the CSV file or purely in memory.
+// Items are addressed by their ID, which is assigned by the store and never reused.
+type ItemStore interface {
+	// Initialize loads the items from the backend, calling it again reloads them.
+	Initialize() error
+	// GetAllItems returns a copy of all items.
+	GetAllItems() []Item
+	// GetItemByID returns the item with the passed ID.
+	GetItemByID(id int) (Item, error)
+	// AddItem adds the passed item to the inventory and returns its new ID.
+	AddItem(newItem Item) (int, error)
+	// UpdateItem replaces the item with the passed ID.
+	UpdateItem(id int, updatedItem Item) error
+	// UpdateItems applies the passed update to every item with one of the IDs, all of them are saved together.
+	// The passed files are written in the same transaction, so a change that spans several files is atomic.
+	UpdateItems(ids []int, update func(item *Item), files ...Storage.FileWrite) error
+	// RemoveItem marks the item with the passed ID as deleted.
+	RemoveItem(id int) error
+	// RestoreItem brings a deleted item back into the active inventory. The optional update is applied in the same
+	// change, e.g. to replace a category that no longer exists.
+	RestoreItem(id int, update func(item *Item)) error
+	// PurgeItems removes the deleted items with the passed IDs for good, they are moved to the archive.
+	PurgeItems(ids []int) error
+	// PurgeDeletedBefore purges every item that was deleted before the passed time and returns how many.
+	PurgeDeletedBefore(cutoff time.Time) (int, error)
+	// SetItemStates puts the items into the passed states, it is used to undo and redo changes.
+	// Like in UpdateItems, the passed files are written in the same transaction.
+	SetItemStates(states []ItemState, files ...Storage.FileWrite) error
+	// LoadReport returns the rows that could not be loaded by the last Initialize.
+	LoadReport() LoadReport
+	// AcknowledgeLoadReport allows saving again after rows could not be loaded.
+	AcknowledgeLoadReport()
+}
+
+// ItemState is the complete state of one item at some point in time, Item is nil when the item did not exist.
+type ItemState struct {
+	ID   int
+	Item *Item
+}
+
+// LoadReport describes the rows that were skipped while loading. As long as it is not acknowledged,
+// saving is blocked, because a save would remove the skipped rows from the data file.
+type LoadReport struct {
+	Issues         []RowIssue
+	QuarantineFile string
+	Acknowledged   bool
+}
+
+// ErrSaveBlocked is returned by changes while a load report with issues was not acknowledged yet
+var ErrSaveBlocked = errors.New("saving is blocked until the rows that could not be loaded are acknowledged")
+
+// *MemoryItemStore: Keeps the inventory in memory only, nothing is persisted.
+// *MemoryItemStore: Hält das Inventar nur im Speicher, es wird nichts gespeichert.
+type MemoryItemStore struct {
+	items  []Item
+	nextID int
+
+	// NumbersIncludeDeleted makes the article numbers of deleted items count as taken as well
+	NumbersIncludeDeleted bool
+}
+
+// *NewMemoryItemStore: Creates an in-memory store that starts with a copy of the passed items.
+// *NewMemoryItemStore: Erstellt einen Speicher im Arbeitsspeicher mit einer Kopie der übergebenen Artikel.
+func NewMemoryItemStore(items []Item) *MemoryItemStore {
+	store := &MemoryItemStore{items: make([]Item, len(items))}
+	copy(store.items, items)
+	store.assignMissingIDs()
+	return store
+}
+
+// *Initialize: Nothing to load for the in-memory store.
+// *Initialize: Für den Speicher im Arbeitsspeicher gibt es nichts zu laden.
+func (s *MemoryItemStore) Initialize() error {
+	return nil
+}
+
+// *GetAllItems: returns a copy of all items
+// *GetAllItems: Gibt eine Kopie aller Artikel zurück.
+func (s *MemoryItemStore) GetAllItems() []Item {
+	allItems := make([]Item, len(s.items))
+	copy(allItems, s.items)
+	return allItems
+}
+
+// *GetItemByID: Returns the item with the passed ID.
+// *GetItemByID: Gibt den Artikel mit der übergebenen ID zurück.
+func (s *MemoryItemStore) GetItemByID(id int) (Item, error) {
+	index, err := s.indexOf(id)
+	if err != nil {
+		return Item{}, err
+	}
+	return s.items[index], nil
+}
+
+// *AddItem: adds the passed Item to the Inventory and assigns a new ID, its article number must not be taken
+// *AddItem: Fügt den übergebenen Artikel dem Inventar hinzu und vergibt eine neue ID, seine Artikelnummer darf nicht vergeben sein.
+func (s *MemoryItemStore) AddItem(newItem Item) (int, error) {
+	s.assignMissingIDs()
+	newItem.ID = 0
+	if err := s.checkArticleNumber(newItem); err != nil {
+		return 0, err
+	}
+	if err := s.checkSerialNumbers(newItem, Item{}); err != nil {
+		return 0, err
+	}
+	newItem.syncQuantity()
+	newItem.fitLocations()
+	newItem.ID = s.nextID
+	s.nextID++
+	s.items = append(s.items, newItem)
+	return newItem.ID, nil
+}
+
+// *UpdateItem: Updates the item with the passed ID, the ID itself stays the same. A new article number must not be taken.
+// *UpdateItem: Aktualisiert den Artikel mit der übergebenen ID, die ID selbst bleibt gleich. Eine neue Artikelnummer darf nicht vergeben sein.
+func (s *MemoryItemStore) UpdateItem(id int, updatedItem Item) error {
+	index, err := s.indexOf(id)
+	if err != nil {
+		return err
+	}
+
+	updatedItem.ID = id
+	// Only a changed number is checked, so items that already share a number can still be edited otherwise
+	if !SameArticleNumber(s.items[index].ArticleNumber, updatedItem.ArticleNumber) {
+		if err := s.checkArticleNumber(updatedItem); err != nil {
+			return err
+		}
+	}
+	if err := s.checkSerialNumbers(updatedItem, s.items[index]); err != nil {
+		return err
+	}
+	updatedItem.syncQuantity()
+	updatedItem.fitLocations()
+	s.items[index] = updatedItem
+	return nil
+}
+
+// *UpdateItems: Applies the update to every item with one of the passed IDs and writes the passed files.
+// *UpdateItems: Wendet die Änderung auf jeden Artikel mit einer der übergebenen IDs an und schreibt die übergebenen Dateien.
+func (s *MemoryItemStore) UpdateItems(ids []int, update func(item *Item), files ...Storage.FileWrite) error {
+	if err := s.updateItems(ids, update); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := Storage.WriteFileAtomic(file.Path, file.Write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// *updateItems: Applies the update to every item with one of the passed IDs, the IDs themselves stay the same.
+// *updateItems: Wendet die Änderung auf jeden Artikel mit einer der übergebenen IDs an, die IDs selbst bleiben gleich.
+func (s *MemoryItemStore) updateItems(ids []int, update func(item *Item)) error {
+	for _, id := range ids {
+		index, err := s.indexOf(id)
+		if err != nil {
+			return err
+		}
+		update(&s.items[index])
+		s.items[index].ID = id
+	}
+	return nil
+}
+
+// *RemoveItem: Marks the item with the passed ID as deleted.
+// *RemoveItem: Markiert den Artikel mit der übergebenen ID als gelöscht.
+func (s *MemoryItemStore) RemoveItem(id int) error {
+	index, err := s.indexOf(id)
+	if err != nil {
+		return err
+	}
+
+	// Mark the item as deleted and set the deletion date
+	now := time.Now()
+	s.items[index].IsDeleted = true
+	s.items[index].DeleteDate = &now
+	return nil
+}
+
+// *RestoreItem: Brings the deleted item with the passed ID back, the update is only applied when it can be restored.
+// *RestoreItem: Stellt den gelöschten Artikel mit der übergebenen ID wieder her, die Änderung wird nur übernommen,
+// wenn er wiederhergestellt werden kann.
+func (s *MemoryItemStore) RestoreItem(id int, update func(item *Item)) error {
+	index, err := s.indexOf(id)
+	if err != nil {
+		return err
+	}
+	if !s.items[index].IsDeleted {
+		return fmt.Errorf("item with ID %d is not deleted", id)
+	}
+	restored := s.items[index]
+	if update != nil {
+		update(&restored)
+		restored.ID = id
+	}
+	if !s.NumbersIncludeDeleted {
+		// The number may have been given to another item while this one was deleted
+		if err := s.checkArticleNumber(restored); err != nil {
+			return err
+		}
+	}
+
+	restored.IsDeleted = false
+	restored.DeleteDate = nil
+	s.items[index] = restored
+	return nil
+}
+
+// *PurgeItems: Removes the deleted items with the passed IDs for good.
+// *PurgeItems: Entfernt die gelöschten Artikel mit den übergebenen IDs endgültig.
+func (s *MemoryItemStore) PurgeItems(ids []int) error {
+	_, err := s.purge(ids)
+	return err
+}
+
+// *PurgeDeletedBefore: Removes all items that were deleted before the passed time for good.
+// *PurgeDeletedBefore: Entfernt alle Artikel, die vor dem übergebenen Zeitpunkt gelöscht wurden, endgültig.
+func (s *MemoryItemStore) PurgeDeletedBefore(cutoff time.Time) (int, error) {
+	purged, err := s.purge(s.deletedBefore(cutoff))
+	return len(purged), err
+}
+
+// *purge: Removes the passed deleted items and returns them, active items can't be purged.
+// *purge: Entfernt die übergebenen gelöschten Artikel und gibt sie zurück, aktive Artikel können nicht entfernt werden.
+func (s *MemoryItemStore) purge(ids []int) ([]Item, error) {
+	selected := map[int]bool{}
+	for _, id := range ids {
+		index, err := s.indexOf(id)
+		if err != nil {
+			return nil, err
+		}
+		if !s.items[index].IsDeleted {
+			return nil, fmt.Errorf("item with ID %d is not deleted, only deleted items can be purged", id)
+		}
+		selected[id] = true
+	}
+
+	var kept, purged []Item
+	for _, item := range s.items {
+		if selected[item.ID] {
+			purged = append(purged, item)
+		} else {
+			kept = append(kept, item)
+		}
+	}
+	s.items = kept
+	return purged, nil
+}
+
+// *deletedBefore: Returns the IDs of the items that were deleted before the passed time.
+// *deletedBefore: Gibt die IDs der Artikel zurück, die vor dem übergebenen Zeitpunkt gelöscht wurden.
+func (s *MemoryItemStore) deletedBefore(cutoff time.Time) []int {
+	var ids []int
+	for _, item := range s.items {
+		if item.IsDeleted && item.DeleteDate != nil && item.DeleteDate.Before(cutoff) {
+			ids = append(ids, item.ID)
+		}
+	}
+	return ids
+}
+
+// *SetItemStates: Replaces, adds or removes the items so they match the passed states and writes the passed files.
+// *SetItemStates: Ersetzt, ergänzt oder entfernt die Artikel, sodass sie den übergebenen Zuständen entsprechen, und
+// schreibt die übergebenen Dateien.
+func (s *MemoryItemStore) SetItemStates(states []ItemState, files ...Storage.FileWrite) error {
+	s.setItemStates(states)
+	for _, file := range files {
+		if err := Storage.WriteFileAtomic(file.Path, file.Write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// *setItemStates: Replaces, adds or removes the items so they match the passed states.
+// *setItemStates: Ersetzt, ergänzt oder entfernt die Artikel, sodass sie den übergebenen Zuständen entsprechen.
+func (s *MemoryItemStore) setItemStates(states []ItemState) {
+	for _, state := range states {
+		index, err := s.indexOf(state.ID)
+		switch {
+		case state.Item == nil && err == nil:
+			s.items = append(s.items[:index], s.items[index+1:]...)
+		case state.Item == nil:
+			// The item doesn't exist, which is the requested state
+		case err == nil:
+			s.items[index] = *state.Item
+			s.items[index].ID = state.ID
+		default:
+			item := *state.Item
+			item.ID = state.ID
+			s.items = append(s.items, item)
+		}
+		// IDs are never reused, even when an added item is removed again
+		if state.ID >= s.nextID {
+			s.nextID = state.ID + 1
+		}
+	}
+}
+
+// *LoadReport: The in-memory store never skips rows.
+// *LoadReport: Der Speicher im Arbeitsspeicher überspringt nie Zeilen.
+func (s *MemoryItemStore) LoadReport() LoadReport {
+	return LoadReport{Acknowledged: true}
+}
+
+// *AcknowledgeLoadReport: Nothing to acknowledge for the in-memory store.
+// *AcknowledgeLoadReport: Für den Speicher im Arbeitsspeicher gibt es nichts zu bestätigen.
+func (s *MemoryItemStore) AcknowledgeLoadReport() {}
+
+// *indexOf: Returns the position of the item with the passed ID.
+// *indexOf: Gibt die Position des Artikels mit der übergebenen ID zurück.
+func (s *MemoryItemStore) indexOf(id int) (int, error) {
+	for index, item := range s.items {
+		if item.ID == id {
+			return index, nil
+		}
+	}
+	return -1, fmt.Errorf("no item with ID %d", id)
+}
+
+// *assignMissingIDs: Gives every item without ID a new one and returns whether anything was assigned.
+// *assignMissingIDs: Vergibt jedem Artikel ohne ID eine neue und gibt zurück, ob etwas vergeben wurde.
+func (s *MemoryItemStore) assignMissingIDs() bool {
+	for _, item := range s.items {
+		if item.ID >= s.nextID {
+			s.nextID = item.ID + 1
+		}
+	}
+	if s.nextID < 1 {
+		s.nextID = 1
+	}
+
+	assigned := false
+	for index := range s.items {
+		if s.items[index].ID == 0 {
+			s.items[index].ID = s.nextID
+			s.nextID++
+			assigned = true
+		}
+	}
+	return assigned
+}
+
+// ErrStaleData is returned when the data file was changed by another process since it was loaded
+var ErrStaleData = errors.New("the inventory was changed by another user since it was loaded")
+
+// *CsvItemStore: Keeps the inventory in memory and writes every change to a CSV file.
+// *CsvItemStore: Hält das Inventar im Speicher und schreibt jede Änderung in eine CSV-Datei.
+type CsvItemStore struct {
+	MemoryItemStore
+	FilePath string
+
+	// fingerprint of the data file as it was loaded or last saved by this process
+	fingerprint Storage.Fingerprint
+	// loaded is false as long as the file could not be read, saving would overwrite it otherwise
+	loaded bool
+	// pendingArchive are purged items that are written to the archive with the next save
+	pendingArchive []Item
+	// pendingFiles are other files that are written in the transaction of the next save
+	pendingFiles []Storage.FileWrite
+	// migrateFrom is the schema version of the file on disk, it is backed up before the first save
+	migrateFrom int
+	report      LoadReport
+}
+
+// *NewCsvItemStore: Creates a store backed by the passed CSV file.
+// *NewCsvItemStore: Erstellt einen Speicher, der auf der übergebenen CSV-Datei basiert.
+func NewCsvItemStore(filePath string) *CsvItemStore {
+	return &CsvItemStore{FilePath: filePath}
+}
+
+// *Initialize: Loads or reloads the inventory, older file versions and files without IDs are upgraded and saved.
+// *Initialize: Lädt das Inventar (erneut), ältere Dateiversionen und Dateien ohne IDs werden aktualisiert und gespeichert.
+func (s *CsvItemStore) Initialize() error {
+	lock, err := Storage.Lock(s.FilePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// Complete or roll back a save that was interrupted by a crash
+	if err := Storage.Recover(filepath.Dir(s.FilePath)); err != nil {
+		return err
+	}
+
+	items, fileVersion, issues, err := readItemsFromFile(s.FilePath)
+	if err != nil {
+		s.loaded = false
+		return err
+	}
+	nextID, err := readNextID(s.FilePath)
+	if err != nil {
+		s.loaded = false
+		return err
+	}
+	fingerprint, err := Storage.FingerprintFile(s.FilePath)
+	if err != nil {
+		s.loaded = false
+		return err
+	}
+	s.items = items
+	s.nextID = nextID
+	s.fingerprint = fingerprint
+	s.migrateFrom = fileVersion
+	s.loaded = true
+
+	// Rows that could not be loaded are kept in the quarantine file, saving waits for the user
+	quarantinePath := quarantineFilePath(s.FilePath)
+	acknowledged := len(issues) == 0 || (s.report.Acknowledged && sameIssues(s.report.Issues, issues))
+	s.report = LoadReport{Issues: issues, QuarantineFile: quarantinePath, Acknowledged: acknowledged}
+	if len(issues) > 0 {
+		if err := writeQuarantine(quarantinePath, issues); err != nil {
+			return err
+		}
+	}
+
+	// Older schema versions and missing IDs are written back right away, unless saving is blocked
+	if (s.assignMissingIDs() || fileVersion < CurrentSchemaVersion) && s.report.Acknowledged {
+		return s.save(&s.MemoryItemStore)
+	}
+	return nil
+}
+
+// *LoadReport: Returns the rows that could not be loaded.
+// *LoadReport: Gibt die Zeilen zurück, die nicht geladen werden konnten.
+func (s *CsvItemStore) LoadReport() LoadReport {
+	return s.report
+}
+
+// *AcknowledgeLoadReport: Allows saving again, the next save removes the skipped rows from the data file.
+// *AcknowledgeLoadReport: Erlaubt das Speichern wieder, das nächste Speichern entfernt die übersprungenen Zeilen aus der Datendatei.
+func (s *CsvItemStore) AcknowledgeLoadReport() {
+	s.report.Acknowledged = true
+}
+
+// *AddItem: adds the passed Item to the Inventory and updates the file
+// *AddItem: Fügt den übergebenen Artikel dem Inventar hinzu und aktualisiert die Datei.
+func (s *CsvItemStore) AddItem(newItem Item) (int, error) {
+	var id int
+	err := s.mutate(func(items *MemoryItemStore) error {
+		var err error
+		id, err = items.AddItem(newItem)
+		return err
+	})
+	return id, err
+}
+
+// *UpdateItem: Updates an item in the inventory and updates the file.
+// *UpdateItem: Aktualisiert einen Artikel im Inventar und aktualisiert die Datei.
+func (s *CsvItemStore) UpdateItem(id int, updatedItem Item) error {
+	return s.mutate(func(items *MemoryItemStore) error {
+		return items.UpdateItem(id, updatedItem)
+	})
+}
+
+// *UpdateItems: Updates several items at once and saves them together with the passed files.
+// *UpdateItems: Aktualisiert mehrere Artikel auf einmal und speichert sie zusammen mit den übergebenen Dateien.
+func (s *CsvItemStore) UpdateItems(ids []int, update func(item *Item), files ...Storage.FileWrite) error {
+	return s.mutate(func(items *MemoryItemStore) error {
+		s.pendingFiles = files
+		return items.updateItems(ids, update)
+	})
+}
+
+// *RemoveItem: Marks the item with the passed ID as deleted and updates the file.
+// *RemoveItem: Markiert den Artikel mit der übergebenen ID als gelöscht und aktualisiert die Datei.
+func (s *CsvItemStore) RemoveItem(id int) error {
+	return s.mutate(func(items *MemoryItemStore) error {
+		return items.RemoveItem(id)
+	})
+}
+
+// *RestoreItem: Brings the deleted item with the passed ID back and updates the file.
+// *RestoreItem: Stellt den gelöschten Artikel mit der übergebenen ID wieder her und aktualisiert die Datei.
+func (s *CsvItemStore) RestoreItem(id int, update func(item *Item)) error {
+	return s.mutate(func(items *MemoryItemStore) error {
+		return items.RestoreItem(id, update)
+	})
+}
+
+// *SetItemStates: Puts the items into the passed states and saves them together with the passed files.
+// *SetItemStates: Bringt die Artikel in die übergebenen Zustände und speichert sie zusammen mit den übergebenen Dateien.
+func (s *CsvItemStore) SetItemStates(states []ItemState, files ...Storage.FileWrite) error {
+	return s.mutate(func(items *MemoryItemStore) error {
+		s.pendingFiles = files
+		items.setItemStates(states)
+		return nil
+	})
+}
+
+// *PurgeItems: Moves the deleted items with the passed IDs to the archive file.
+// *PurgeItems: Verschiebt die gelöschten Artikel mit den übergebenen IDs in die Archivdatei.
+func (s *CsvItemStore) PurgeItems(ids []int) error {
+	return s.mutate(func(items *MemoryItemStore) error {
+		purged, err := items.purge(ids)
+		s.pendingArchive = purged
+		return err
+	})
+}
+
+// *PurgeDeletedBefore: Moves all items deleted before the passed time to the archive file.
+// *PurgeDeletedBefore: Verschiebt alle vor dem übergebenen Zeitpunkt gelöschten Artikel in die Archivdatei.
+func (s *CsvItemStore) PurgeDeletedBefore(cutoff time.Time) (int, error) {
+	if len(s.deletedBefore(cutoff)) == 0 {
+		return 0, nil // Nothing to do, the file stays untouched
+	}
+	count := 0
+	err := s.mutate(func(items *MemoryItemStore) error {
+		purged, err := items.purge(items.deletedBefore(cutoff))
+		s.pendingArchive = purged
+		count = len(purged)
+		return err
+	})
+	return count, err
+}
+
+// *mutate: Applies a change to a copy of the inventory and only keeps it once it was saved, then records it in the audit log.
+// The data file is locked for the whole change and must not have been changed by another process.
+// *mutate: Wendet eine Änderung auf eine Kopie des Inventars an, übernimmt sie erst nach dem Speichern und protokolliert sie.
+// Die Datendatei ist während der Änderung gesperrt und darf nicht von einem anderen Prozess geändert worden sein.
+func (s *CsvItemStore) mutate(change func(items *MemoryItemStore) error) error {
+	lock, err := Storage.Lock(s.FilePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if !s.loaded {
+		return fmt.Errorf("the inventory could not be loaded, saving is disabled to protect %s", s.FilePath)
+	}
+	if !s.report.Acknowledged {
+		return ErrSaveBlocked
+	}
+	current, err := Storage.FingerprintFile(s.FilePath)
+	if err != nil {
+		return err
+	}
+	if !current.SameContent(s.fingerprint) {
+		return ErrStaleData
+	}
+
+	changed := NewMemoryItemStore(s.items)
+	changed.nextID = s.nextID
+	changed.NumbersIncludeDeleted = s.NumbersIncludeDeleted
+	defer func() {
+		s.pendingArchive = nil
+		s.pendingFiles = nil
+	}()
+	if err := change(changed); err != nil {
+		return err
+	}
+	before := s.items
+	if err := s.save(changed); err != nil {
+		return err
+	}
+
+	// The log is written after the change was committed, so it never records a change that was not saved
+	if err := Audit.Record(s.FilePath, itemAuditEntries(before, changed.items)); err != nil {
+		return fmt.Errorf("the change was saved, but could not be written to the audit log: %v", err)
+	}
+	return nil
+}
+
+// *save: Snapshots the data file, then writes the passed items and the ID sequence as one crash-safe transaction and keeps them.
+// *save: Sichert die Datendatei, schreibt dann die übergebenen Artikel und die ID-Sequenz als eine absturzsichere Transaktion und übernimmt sie.
+func (s *CsvItemStore) save(changed *MemoryItemStore) error {
+	// Keep a copy of a file in an older schema version before it is overwritten for the first time
+	if s.migrateFrom < CurrentSchemaVersion {
+		if _, err := backupBeforeMigration(s.FilePath, s.migrateFrom); err != nil {
+			return err
+		}
+	}
+
+	if err := Storage.TakeSnapshot(s.FilePath); err != nil {
+		return err
+	}
+
+	transaction := Storage.NewTransaction()
+	err := transaction.WriteFile(s.FilePath, func(w io.Writer) error {
+		return writeItems(w, changed.items)
+	})
+	if err != nil {
+		return err
+	}
+	err = transaction.WriteFile(sequenceFilePath(s.FilePath), func(w io.Writer) error {
+		return writeNextID(w, changed.nextID)
+	})
+	if err != nil {
+		return err
+	}
+	if len(s.pendingArchive) > 0 {
+		// Purged items leave the data file and enter the archive in the same transaction
+		archivePath := archiveFilePath(s.FilePath)
+		err = transaction.WriteFile(archivePath, func(w io.Writer) error {
+			return appendToArchive(w, archivePath, s.pendingArchive)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, file := range s.pendingFiles {
+		if err := Storage.TakeSnapshot(file.Path); err != nil {
+			transaction.Rollback()
+			return err
+		}
+		if err := transaction.WriteFile(file.Path, file.Write); err != nil {
+			return err
+		}
+	}
+	if err := transaction.Commit(); err != nil {
+		return err
+	}
+
+	fingerprint, err := Storage.FingerprintFile(s.FilePath)
+	if err != nil {
+		return err
+	}
+	s.items = changed.items
+	s.nextID = changed.nextID
+	s.fingerprint = fingerprint
+	s.migrateFrom = CurrentSchemaVersion
+	return nil
+}
+
+// *sameIssues: Checks whether both lists contain the same rows.
+// *sameIssues: Prüft, ob beide Listen dieselben Zeilen enthalten.
+func sameIssues(first, second []RowIssue) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for index := range first {
+		if first[index].Content != second[index].Content {
+			return false
+		}
+	}
+	return true
+}
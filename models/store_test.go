@@ -0,0 +1,139 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// deletedAt returns a copy of the item that was deleted at the passed time
+func deletedAt(item Item, deleted time.Time) Item {
+	item.IsDeleted = true
+	item.DeleteDate = &deleted
+	return item
+}
+
+func testItems() []Item {
+	now := time.Now()
+	return []Item{
+		{ID: 1, ArticleName: "Monitor", Category: "Monitore", ArticleNumber: "M001", Supplier: "Dell", Quantity: 3},
+		deletedAt(Item{ID: 2, ArticleName: "Maus", Category: "Zubehör", ArticleNumber: "Z001", Supplier: "Logitech", Quantity: 10}, now.AddDate(0, 0, -40)),
+		deletedAt(Item{ID: 3, ArticleName: "Tastatur", Category: "Zubehör", ArticleNumber: "Z002", Supplier: "Logitech", Quantity: 5}, now.AddDate(0, 0, -5)),
+	}
+}
+
+// itemIDs returns the IDs of the items in their order
+func itemIDs(items []Item) []int {
+	var ids []int
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestPurgeItems(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     []int
+		wantErr bool
+		want    []int
+	}{
+		{name: "deleted items", ids: []int{2, 3}, want: []int{1}},
+		{name: "active item", ids: []int{1}, wantErr: true, want: []int{1, 2, 3}},
+		{name: "active and deleted item", ids: []int{2, 1}, wantErr: true, want: []int{1, 2, 3}},
+		{name: "unknown item", ids: []int{9}, wantErr: true, want: []int{1, 2, 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewMemoryItemStore(testItems())
+			err := store.PurgeItems(test.ids)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("PurgeItems(%v) returned %v, want an error: %v", test.ids, err, test.wantErr)
+			}
+			if got := itemIDs(store.GetAllItems()); !slices.Equal(got, test.want) {
+				t.Errorf("items %v are left, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPurgeDeletedBefore(t *testing.T) {
+	store := NewMemoryItemStore(testItems())
+	count, err := store.PurgeDeletedBefore(time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("purged %d items, want 1", count)
+	}
+	if got := itemIDs(store.GetAllItems()); !slices.Equal(got, []int{1, 3}) {
+		t.Errorf("items %v are left, want [1 3]", got)
+	}
+
+	// The IDs of purged items are never given to new items
+	id, err := store.AddItem(Item{ArticleName: "Headset", ArticleNumber: "Z003"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 4 {
+		t.Errorf("new item got ID %d, want 4", id)
+	}
+}
+
+func TestPurgeMovesItemsToArchive(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), FileData)
+	if err := CreateDataFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	store := NewCsvItemStore(filePath)
+	if err := store.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range testItems() {
+		id, err := store.AddItem(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.IsDeleted {
+			if err := store.UpdateItems([]int{id}, func(added *Item) { *added = item }); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := store.PurgeItems([]int{2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.PurgeDeletedBefore(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The purged items are only in the archive, each with its purge date
+	archive, err := os.ReadFile(archiveFilePath(filePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(archive)), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], ";PurgeDate") || !strings.HasPrefix(lines[1], "2;Maus;") || !strings.HasPrefix(lines[2], "3;Tastatur;") {
+		t.Errorf("archive contains:\n%s\nwant one header and the rows of items 2 and 3", archive)
+	}
+
+	reloaded := NewCsvItemStore(filePath)
+	if err := reloaded.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if got := itemIDs(reloaded.GetAllItems()); !slices.Equal(got, []int{1}) {
+		t.Errorf("data file contains items %v after purging, want [1]", got)
+	}
+	// The ID sequence survives the purge, so a purged ID is not given out again after a restart
+	id, err := reloaded.AddItem(Item{ArticleName: "Headset", ArticleNumber: "Z003"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 4 {
+		t.Errorf("new item got ID %d after a restart, want 4", id)
+	}
+}
@@ -13,7 +13,16 @@ func ShowExecuteCommandMenu() {
 	# -3- Article booking
 	# -4- Change article information
 	#
+	# -5- Check out to employee
+	# -6- Check in from employee
+	# -7- Show who has what
+	#
+	# -8- Show articles by category
 	# -9- Show articles
+	# -10- Show articles below minimum stock
+	#
+	# -U- Undo last change
+	# -R- Redo last undone change
 	#
 	# -C- CLEAR VIEW AND SHOW MENU
 	# -Q- EXIT INVENTORY APP
@@ -29,14 +38,44 @@ func ShowHiddenCommandMenu() {
 	# -1- Show suppliers
 	# -2- Add supplier
 	# -3- Delete supplier
+	# -4- Rename supplier
+	# -5- Edit supplier details
 	#
 	# -11- Show categories
 	# -12- Add category
 	# -13- Delete category
+	# -14- Rename category
+	# -15- Move category
+	# -16- Category attributes
+	# -17- Article number patterns
+	# -18- Minimum stock levels
+	#
+	# -21- Employees
+	# -31- Locations
 	#
-	# -ID- Show deleted Articles
+	# -ID- Show, restore or purge deleted Articles
 	# -IA- Show all Articles
 	#
+	# -B- Backups: show, compare and restore
+	# -AL- Audit log: changes by item, user and date
+	#
 	# -C- SHOW MAIN MENU
 	`)
 }
+
+// ShowFirstRunMenu shows the options to set up missing data files
+func ShowFirstRunMenu(missingFiles []string) {
+	fmt.Println("⚠️ The inventory is not set up yet, these files are missing:")
+	for _, file := range missingFiles {
+		fmt.Printf("   - %s\n", file)
+	}
+	fmt.Println(`
+	###########################################
+	#************* FIRST START ****************
+	#******** CHOOSE YOUR OPTION BELOW ********
+	# -1- Create empty files
+	# -2- Create files with default IT categories
+	#
+	# -Q- EXIT INVENTORY APP
+	`)
+}
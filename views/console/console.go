@@ -4,25 +4,62 @@ import (
 	"bufio"
 	"fmt"
 	"it_inventar/models"
+	"it_inventar/models/Attribute"
+	"it_inventar/models/Audit"
+	"it_inventar/models/Category"
+	"it_inventar/models/Ledger"
+	"it_inventar/models/Storage"
 	"log"
+	"maps"
 	"os"
 	"os/exec"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// PageSize is the number of rows shown per page, it is set from the configuration
+var PageSize = 10
+
+// BelowMinimum returns the passed items whose stock is below their minimum, their rows are marked in the item
+// list. It is set by the controller, without it no row is marked.
+var BelowMinimum func(items []models.Item) []models.Item
+
+// belowMinimumMarker ends the rows of the items below their minimum stock. It is plain text, so it is shown by
+// consoles without support for terminal colors as well, and put at the end so the columns stay aligned.
+const belowMinimumMarker = " ⚠️"
+
 const (
 	InitialPage = 0
-	PageSize    = 10
 
 	ExitStatusCodeNoError int = 0
 	// ItemDetailsMessage or the output of article information.
 	ItemDetailsMessage = "Item: %s | Category: %s (%s) | %d pieces | Notes: %s"
 )
 
+// Column is an additional column of the item list, e.g. an attribute or the stock at a location
+type Column struct {
+	Header string
+	Value  func(item models.Item) string
+}
+
+// *AttributeColumns: Returns a column for each of the passed attributes.
+// *AttributeColumns: Gibt für jedes der übergebenen Attribute eine Spalte zurück.
+func AttributeColumns(names []string) []Column {
+	columns := make([]Column, len(names))
+	for index, name := range names {
+		columns[index] = Column{Header: name, Value: func(item models.Item) string { return item.Attributes[name] }}
+	}
+	return columns
+}
+
 // *ShowAllItems: Displays all items in the inventory with dynamically calculated column widths for better readability.
+// The passed columns, e.g. attributes, are shown as additional columns. Items below their minimum stock are marked.
 // *ShowAllItems: Zeigt alle Artikel im Inventar mit dynamisch berechneten Spaltenbreiten für bessere Lesbarkeit an.
-func ShowAllItems(items []models.Item, startIndex int, showDeletedDate bool) {
+// Die übergebenen Spalten, z.B. Attribute, werden als zusätzliche Spalten angezeigt. Artikel unter ihrem Mindestbestand
+// werden markiert.
+func ShowAllItems(items []models.Item, showDeletedDate bool, extraColumns ...Column) {
 	// Calculate the maximum length for each column
 	maxArticleNameLen := len("Item Name")
 	maxArticleCategoryLen := len("Category")
@@ -31,6 +68,10 @@ func ShowAllItems(items []models.Item, startIndex int, showDeletedDate bool) {
 	maxQuantityLen := len("Quantity [pcs]")
 	maxNoteLen := len("Notes")
 	maxDeleteDateLen := len("Deleted At")
+	maxAttributeLens := make([]int, len(extraColumns))
+	for index, column := range extraColumns {
+		maxAttributeLens[index] = len(column.Header)
+	}
 
 	// Iterate through the items to find the maximum length for each column
 	for _, item := range items {
@@ -52,6 +93,9 @@ func ShowAllItems(items []models.Item, startIndex int, showDeletedDate bool) {
 		if len(item.Note) > maxNoteLen {
 			maxNoteLen = len(item.Note)
 		}
+		for index, column := range extraColumns {
+			maxAttributeLens[index] = max(maxAttributeLens[index], len(column.Value(item)))
+		}
 		if showDeletedDate && item.DeleteDate != nil {
 			formattedDate := item.DeleteDate.Format("02.01.2006 / 15:04")
 			if len(formattedDate) > maxDeleteDateLen {
@@ -60,9 +104,23 @@ func ShowAllItems(items []models.Item, startIndex int, showDeletedDate bool) {
 		}
 	}
 
+	// The additional columns are appended to every line
+	attributeCells := func(values func(column Column) string) string {
+		var cells strings.Builder
+		for index, column := range extraColumns {
+			fmt.Fprintf(&cells, " %-*s |", maxAttributeLens[index], values(column))
+		}
+		return cells.String()
+	}
+	attributesHeader := attributeCells(func(column Column) string { return column.Header })
+	attributesWidth := 0
+	for _, width := range maxAttributeLens {
+		attributesWidth += width + 3
+	}
+
 	// Display header with dynamically calculated column widths
 	if showDeletedDate {
-		fmt.Printf("%5s | %-*s | %-*s | %-*s | %-*s | %-*s | %-*s | %-*s |\n",
+		fmt.Printf("%5s | %-*s | %-*s | %-*s | %-*s | %-*s | %-*s | %-*s |%s\n",
 			"ID",
 			maxArticleNameLen, "Item Name",
 			maxArticleCategoryLen, "Category",
@@ -70,47 +128,66 @@ func ShowAllItems(items []models.Item, startIndex int, showDeletedDate bool) {
 			maxSupplierLen, "Supplier",
 			maxQuantityLen, "Quantity [pcs]",
 			maxNoteLen, "Notes",
-			maxDeleteDateLen, "Deleted At")
-		ShowMessage(strings.Repeat("-", maxArticleNameLen+maxArticleCategoryLen+maxArticleNumberLen+maxSupplierLen+maxQuantityLen+maxNoteLen+maxDeleteDateLen+35))
+			maxDeleteDateLen, "Deleted At",
+			attributesHeader)
+		ShowMessage(strings.Repeat("-", maxArticleNameLen+maxArticleCategoryLen+maxArticleNumberLen+maxSupplierLen+maxQuantityLen+maxNoteLen+maxDeleteDateLen+attributesWidth+35))
 	} else {
-		fmt.Printf("%5s | %-*s | %-*s | %-*s | %-*s | %-*s | %-*s |\n",
+		fmt.Printf("%5s | %-*s | %-*s | %-*s | %-*s | %-*s | %-*s |%s\n",
 			"ID",
 			maxArticleNameLen, "Item Name",
 			maxArticleCategoryLen, "Category",
 			maxArticleNumberLen, "Item No.",
 			maxSupplierLen, "Supplier",
 			maxQuantityLen, "Quantity [pcs]",
-			maxNoteLen, "Notes")
-		ShowMessage(strings.Repeat("-", maxArticleNameLen+maxArticleCategoryLen+maxArticleNumberLen+maxSupplierLen+maxQuantityLen+maxNoteLen+25))
+			maxNoteLen, "Notes",
+			attributesHeader)
+		ShowMessage(strings.Repeat("-", maxArticleNameLen+maxArticleCategoryLen+maxArticleNumberLen+maxSupplierLen+maxQuantityLen+maxNoteLen+attributesWidth+25))
 	}
 
-	// Display items with sequential ID
-	for index, item := range items {
+	marked := map[int]bool{}
+	if BelowMinimum != nil {
+		for _, item := range BelowMinimum(items) {
+			marked[item.ID] = true
+		}
+	}
+
+	// Display items with their unique ID
+	for _, item := range items {
+		attributes := attributeCells(func(column Column) string { return column.Value(item) })
+		if marked[item.ID] {
+			// The marker is printed before the line break below
+			attributes += belowMinimumMarker
+		}
 		if showDeletedDate {
 			var deleteDate string
 			if item.DeleteDate != nil {
 				deleteDate = item.DeleteDate.Format("02.01.2006 / 15:04")
 			}
-			fmt.Printf("%5d | %-*s | %-*s | %-*s | %-*s | %-*d | %-*s | %-*s |\n",
-				startIndex+index+1,
+			fmt.Printf("%5d | %-*s | %-*s | %-*s | %-*s | %-*d | %-*s | %-*s |%s\n",
+				item.ID,
 				maxArticleNameLen, item.ArticleName,
 				maxArticleCategoryLen, item.Category,
 				maxArticleNumberLen, item.ArticleNumber,
 				maxSupplierLen, item.Supplier,
 				maxQuantityLen, item.Quantity,
 				maxNoteLen, item.Note,
-				maxDeleteDateLen, deleteDate)
+				maxDeleteDateLen, deleteDate,
+				attributes)
 		} else {
-			fmt.Printf("%5d | %-*s | %-*s | %-*s | %-*s | %-*d | %-*s |\n",
-				startIndex+index+1,
+			fmt.Printf("%5d | %-*s | %-*s | %-*s | %-*s | %-*d | %-*s |%s\n",
+				item.ID,
 				maxArticleNameLen, item.ArticleName,
 				maxArticleCategoryLen, item.Category,
 				maxArticleNumberLen, item.ArticleNumber,
 				maxSupplierLen, item.Supplier,
 				maxQuantityLen, item.Quantity,
-				maxNoteLen, item.Note)
+				maxNoteLen, item.Note,
+				attributes)
 		}
 	}
+	if len(marked) > 0 {
+		ShowMessage(fmt.Sprintf("⚠️ %d article(s) below their minimum stock are marked with%s.", len(marked), belowMinimumMarker))
+	}
 }
 
 // *AskForInput: Reads user input from the console until a line break is detected and returns the input.
@@ -205,8 +282,8 @@ func InputPageEnd() bool {
 
 // *ChecksInventory Checks if the inventory is empty and returns to the main menu if it is.
 // *ChecksInventory Überprüft, ob das Inventar leer ist, und kehrt zum Hauptmenü zurück, wenn es leer ist.
-func ChecksInventory() bool {
-	if len(models.GetAllItems()) == 0 {
+func ChecksInventory(items []models.Item) bool {
+	if len(items) == 0 {
 		ShowMessage("❌ No items available.")
 		ShowContinue()
 		Clear()
@@ -277,14 +354,16 @@ func PageIndexUserInput(choice string, page *int, end int, items []models.Item)
 			return true, nil, 0
 		}
 	} else {
-		// Check whether the input is a valid ID
-		rowId := models.StringToInt(choice)
-		if rowId < 1 || rowId > len(items) {
-			MessageGeneralInvalidID()
-			ShowContinue()
-			return false, nil, 0
+		// Check whether the input is the ID of one of the listed items
+		id := models.StringToInt(choice)
+		for index := range items {
+			if items[index].ID == id {
+				return false, &items[index], id
+			}
 		}
-		return false, &items[rowId-1], rowId
+		MessageGeneralInvalidID()
+		ShowContinue()
+		return false, nil, 0
 	}
 	return false, nil, 0
 }
@@ -376,10 +455,10 @@ func askForInput(fieldName string, defaultValue string, isEditing bool, validate
 
 // *HandleViewItemsGeneric: shows a paginated list of items and allows you to navigate between pages.
 // *HandleViewItemsGeneric: zeigt eine paginierte Liste von Gegenständen und ermöglicht die Navigation zwischen den Seiten.
-func HandleViewItemsGeneric(items []models.Item, showDeletedDate bool) {
+func HandleViewItemsGeneric(items []models.Item, showDeletedDate bool, extraColumns ...Column) {
 	Clear()
 
-	if ChecksInventory() {
+	if ChecksInventory(items) {
 		return
 	}
 
@@ -388,7 +467,7 @@ func HandleViewItemsGeneric(items []models.Item, showDeletedDate bool) {
 		// Calculation of the start and end indices for the current page
 		start, end := PageIndexCalculate(page, PageSize, len(items))
 		// Display of articles on the current page
-		ShowAllItems(items[start:end], start, showDeletedDate)
+		ShowAllItems(items[start:end], showDeletedDate, extraColumns...)
 		choice := PageIndexView()
 
 		if choice == "c" {
@@ -408,7 +487,7 @@ func HandleViewItemsGeneric(items []models.Item, showDeletedDate bool) {
 // *SelectItem: Zeigt eine paginierte Liste von Artikeln an und gibt den ausgewählten Artikel zurück.
 func SelectItem(items []string, pageSize int, itemType string) string {
 	page := InitialPage
-	totalPages := (len(items) + pageSize - 1) / pageSize
+	totalPages := max(1, (len(items)+pageSize-1)/pageSize)
 
 	for {
 		start, end := PageIndexCalculate(page, pageSize, len(items))
@@ -448,11 +527,26 @@ func SelectItem(items []string, pageSize int, itemType string) string {
 // *HandleAddSelectItem: Checks if the user is in edit mode and displays the current selection before allowing a new selection.
 // *HandleAddSelectItem: Überprüft, ob der Benutzer im Bearbeitungsmodus ist, und zeigt die aktuelle Auswahl an, bevor eine neue Auswahl getroffen wird.
 func HandleAddSelectItem(currentItem string, items []string, itemType string, isEditing bool) string {
-	if !isEditing {
+	return handleAddSelect(currentItem, isEditing, func() string {
 		return SelectItem(items, PageSize, itemType)
+	})
+}
+
+// *HandleAddSelectCategory: Like HandleAddSelectItem, but the category is chosen by drilling down the category tree.
+// *HandleAddSelectCategory: Wie HandleAddSelectItem, aber die Kategorie wird schrittweise im Kategorienbaum ausgewählt.
+func HandleAddSelectCategory(currentItem string, nodes []Category.Node, isEditing bool) string {
+	return handleAddSelect(currentItem, isEditing, func() string {
+		return SelectCategory(nodes, PageSize)
+	})
+}
+
+// handleAddSelect runs the selection, in edit mode after showing the current value, which is kept when nothing is chosen
+func handleAddSelect(currentItem string, isEditing bool, selectItem func() string) string {
+	if !isEditing {
+		return selectItem()
 	} else {
 		ShowMessage(fmt.Sprintf("Current: %s", currentItem))
-		newItem := SelectItem(items, PageSize, itemType)
+		newItem := selectItem()
 		if newItem != "" && newItem != "C" {
 			return newItem
 		}
@@ -463,6 +557,79 @@ func HandleAddSelectItem(currentItem string, items []string, itemType string, is
 	}
 }
 
+// *SelectCategory: Displays the categories one level at a time. Choosing a category with subcategories opens it,
+// where the category itself can be chosen with 0.
+// *SelectCategory: Zeigt die Kategorien Ebene für Ebene an. Die Wahl einer Kategorie mit Unterkategorien öffnet sie,
+// dort kann die Kategorie selbst mit 0 gewählt werden.
+func SelectCategory(nodes []Category.Node, pageSize int) string {
+	parent := ""
+	page := InitialPage
+
+	for {
+		children := Category.Children(nodes, parent)
+		totalPages := max(1, (len(children)+pageSize-1)/pageSize)
+		start, end := PageIndexCalculate(page, pageSize, len(children))
+
+		fmt.Println("Please select a Category from the list:")
+		if parent != "" {
+			fmt.Printf("In: %s\n", Category.Path(nodes, parent))
+			fmt.Printf("0: %s (this category)\n", parent)
+		}
+		for i := start; i < end; i++ {
+			marker := ""
+			if Category.HasChildren(nodes, children[i].CategoryName) {
+				marker = " >"
+			}
+			fmt.Printf("%d: %s%s\n", i+1, children[i].CategoryName, marker)
+		}
+
+		if totalPages > 1 {
+			fmt.Printf("Page %d of %d.\n", page+1, totalPages)
+		}
+
+		var choice string
+		if parent == "" {
+			choice = PageIndexPrompt("Category")
+		} else {
+			fmt.Println("Enter the ID of the Category, press [Enter] for next page, [b] to go up or [c] to return to the main menu.")
+			choice = AskForInput()
+		}
+		choice = strings.TrimSpace(choice)
+
+		switch {
+		case strings.ToLower(choice) == "c":
+			Clear()
+			ShowExecuteCommandMenu()
+			return "C"
+		case strings.ToLower(choice) == "b" && parent != "":
+			for _, node := range nodes {
+				if node.CategoryName == parent {
+					parent = node.Parent
+					break
+				}
+			}
+			page = InitialPage
+		case choice == "":
+			page = (page + 1) % totalPages
+		case choice == "0" && parent != "":
+			return parent
+		default:
+			id, err := strconv.Atoi(choice)
+			if err == nil && id > 0 && id <= len(children) {
+				chosen := children[id-1].CategoryName
+				if !Category.HasChildren(nodes, chosen) {
+					return chosen
+				}
+				parent = chosen
+				page = InitialPage
+				continue
+			}
+			MessageGeneralInvalidID()
+			ShowContinue()
+		}
+	}
+}
+
 // DisplaySuppliers displays a paginated list of suppliers
 func DisplaySuppliers(suppliers []string, start, end int) {
 	ShowMessage("* Available suppliers:")
@@ -489,19 +656,20 @@ func ShowEndOfSupplier() {
 	ShowMessage("End of supplier list reached.")
 }
 
-// DisplayCategories displays a paginated list of categories
-func DisplayCategories(suppliers []string, start, end int) {
+// DisplayCategories displays a paginated category tree with the number of articles of every category and its subcategories
+func DisplayCategories(nodes []Category.Node, articleCounts map[string]int, start, end int) {
 	ShowMessage("* Available Categories:")
-	for i := start; i < end && i < len(suppliers); i++ {
-		fmt.Printf("%d. %s\n", i+1, suppliers[i]) // Add 1 to i for correct numbering
+	for i := start; i < end && i < len(nodes); i++ {
+		indent := strings.Repeat("   ", nodes[i].Depth)
+		fmt.Printf("%d. %s%s (%d articles)\n", i+1, indent, nodes[i].CategoryName, articleCounts[nodes[i].CategoryName]) // Add 1 to i for correct numbering
 	}
 }
 
-// ShowCategoriesList shows the list of items with their index and details
-func ShowCategoriesList(Categories []string) {
+// ShowCategoriesList shows the category tree with the index of every category
+func ShowCategoriesList(nodes []Category.Node) {
 	ShowMessage("* Showing Existing Categories *")
-	for i, category := range Categories {
-		fmt.Printf("%d. %s\n", i+1, category)
+	for i, node := range nodes {
+		fmt.Printf("%d. %s%s\n", i+1, strings.Repeat("   ", node.Depth), node.CategoryName)
 	}
 }
 
@@ -542,3 +710,547 @@ func GetUserInput() string {
 	input, _ := reader.ReadString('\n')
 	return strings.TrimSpace(input)
 }
+
+// *AskForStaleDataResolution: Warns that another user saved the inventory and asks how to continue.
+// *AskForStaleDataResolution: Warnt, dass ein anderer Benutzer das Inventar gespeichert hat, und fragt nach dem weiteren Vorgehen.
+func AskForStaleDataResolution() string {
+	ShowMessage("⚠️ The inventory was changed by another user since it was loaded.")
+	ShowMessage("[r] Reload the inventory and apply my change again")
+	ShowMessage("[c] Discard my change and reload the inventory")
+	return AskForInput()
+}
+
+// *ShowArticleNumberConflict: Shows the item that already has the entered article number.
+// *ShowArticleNumberConflict: Zeigt den Artikel an, der die eingegebene Artikelnummer bereits hat.
+func ShowArticleNumberConflict(number string, existing models.Item) {
+	ShowMessage(fmt.Sprintf("❌ The article number %s is already used by this item:", number))
+	ShowAllItems([]models.Item{existing}, existing.IsDeleted)
+}
+
+// *AskForArticleNumberConflictResolution: Asks whether to enter another number, edit the existing item or cancel.
+// *AskForArticleNumberConflictResolution: Fragt, ob eine andere Nummer eingegeben, der bestehende Artikel bearbeitet oder abgebrochen werden soll.
+func AskForArticleNumberConflictResolution(canEdit bool) string {
+	ShowMessage("[n] Enter another article number")
+	if canEdit {
+		ShowMessage("[e] Edit the existing item instead")
+	} else {
+		ShowMessage("   The existing item is deleted, it can be restored in the deleted items menu")
+	}
+	ShowMessage("[c] Cancel and return to the main menu")
+	return AskForInput()
+}
+
+// *ShowLoadIssues: Shows the rows of the data file that could not be loaded.
+// *ShowLoadIssues: Zeigt die Zeilen der Datendatei an, die nicht geladen werden konnten.
+func ShowLoadIssues(report models.LoadReport) {
+	ShowMessage(fmt.Sprintf("⚠️ %d row(s) of the data file could not be loaded:", len(report.Issues)))
+	for _, issue := range report.Issues {
+		fmt.Printf("  Line %d: %s\n    %s\n", issue.Line, issue.Reason, issue.Content)
+	}
+	ShowMessage(fmt.Sprintf("The rows were copied to %s.", report.QuarantineFile))
+	ShowMessage("Saving is blocked, because the next save removes these rows from the data file.")
+}
+
+// *AskForLoadIssuesAcknowledgement: Asks the user to acknowledge the rows that could not be loaded.
+// *AskForLoadIssuesAcknowledgement: Fordert den Benutzer auf, die nicht geladenen Zeilen zu bestätigen.
+func AskForLoadIssuesAcknowledgement() string {
+	ShowMessage("Acknowledge and allow saving? (y/n)")
+	return AskForInput()
+}
+
+// maxDiffLinesShown limits the preview of a snapshot to keep it readable
+const maxDiffLinesShown = 40
+
+// *ShowSnapshotList: Shows the available snapshots, newest first.
+// *ShowSnapshotList: Zeigt die verfügbaren Sicherungen an, die neueste zuerst.
+func ShowSnapshotList(snapshots []Storage.Snapshot) {
+	ShowMessage("* Available snapshots *")
+	for i, snapshot := range snapshots {
+		fmt.Printf("%d. %s | %s\n", i+1, snapshot.Time.Format("02.01.2006 / 15:04:05"), snapshot.FileName)
+	}
+}
+
+// *ShowSnapshotDiff: Shows what restoring the snapshot would change, '+' lines come back, '-' lines are removed.
+// *ShowSnapshotDiff: Zeigt, was die Wiederherstellung ändern würde, '+' Zeilen kommen zurück, '-' Zeilen werden entfernt.
+func ShowSnapshotDiff(snapshot Storage.Snapshot, diff []Storage.DiffLine) {
+	added, removed, shown := 0, 0, 0
+	ShowMessage(fmt.Sprintf("* Changes when restoring %s from %s *", snapshot.FileName, snapshot.Time.Format("02.01.2006 / 15:04:05")))
+	for _, line := range diff {
+		if line.Change == ' ' {
+			continue
+		}
+		if line.Change == '+' {
+			added++
+		} else {
+			removed++
+		}
+		if shown < maxDiffLinesShown {
+			fmt.Printf("%c %s\n", line.Change, line.Text)
+			shown++
+		}
+	}
+	if added+removed == 0 {
+		ShowMessage("The snapshot is identical to the current data.")
+		return
+	}
+	if added+removed > shown {
+		ShowMessage(fmt.Sprintf("... %d more changed lines", added+removed-shown))
+	}
+	ShowMessage(fmt.Sprintf("%d line(s) come back, %d line(s) are removed.", added, removed))
+}
+
+// *ShowDeletedItemActions: Shows what can be done with the selected deleted items.
+// *ShowDeletedItemActions: Zeigt, was mit den ausgewählten gelöschten Artikeln gemacht werden kann.
+func ShowDeletedItemActions() {
+	ShowMessage("[r] Restore\n[p] Purge permanently\n[c] Cancel")
+}
+
+// *ShowAuditEntries: Shows entries of the audit log, one changed field per row.
+// *ShowAuditEntries: Zeigt Einträge des Änderungsprotokolls an, ein geändertes Feld pro Zeile.
+func ShowAuditEntries(entries []Audit.Entry) {
+	ShowMessage("* Audit log *")
+	for _, entry := range entries {
+		fmt.Printf("%s | %s | %s %s %s | %s: %q -> %q\n",
+			entry.Time.Local().Format("02.01.2006 / 15:04:05"), entry.User, entry.Operation, entry.Entity, entry.Key,
+			entry.Field, entry.Before, entry.After)
+	}
+}
+
+// *ShowReferencingItems: Shows the items that still refer to the category or supplier that should be deleted.
+// *ShowReferencingItems: Zeigt die Artikel an, die noch auf die zu löschende Kategorie oder den Lieferanten verweisen.
+func ShowReferencingItems(kind, name string, items []models.Item) {
+	ShowMessage(fmt.Sprintf("⚠️ %d item(s) still use the %s '%s':", len(items), kind, name))
+	ShowAllItems(items, true)
+}
+
+// *AskForReferenceResolution: Asks what happens to the items of a category or supplier that is deleted.
+// *AskForReferenceResolution: Fragt, was mit den Artikeln einer gelöschten Kategorie oder eines gelöschten Lieferanten geschieht.
+func AskForReferenceResolution(kind string) string {
+	ShowMessage(fmt.Sprintf("[b] Block: keep the %s", kind))
+	ShowMessage(fmt.Sprintf("[r] Reassign the items to another %s", kind))
+	ShowMessage(fmt.Sprintf("[d] Delete the items together with the %s", kind))
+	return AskForInput()
+}
+
+// *ShowReferenceIssues: Shows the items that refer to categories or suppliers which are not in the lists.
+// *ShowReferenceIssues: Zeigt die Artikel an, die auf Kategorien oder Lieferanten verweisen, die nicht in den Listen stehen.
+func ShowReferenceIssues(issues []models.ReferenceIssue) {
+	ShowMessage(fmt.Sprintf("⚠️ %d reference(s) of items point to categories or suppliers that don't exist:", len(issues)))
+	for _, issue := range issues {
+		fmt.Printf("  Item %d: %s '%s'\n", issue.ItemID, issue.Kind, issue.Name)
+	}
+	ShowMessage("[a] Add the missing names to the lists\n[Enter] Continue, the items can be corrected with 'Change article information'")
+}
+
+// *ShowSupplierDetails: Shows the contact and account data of a supplier, empty fields are left out.
+// *ShowSupplierDetails: Zeigt die Kontakt- und Kontodaten eines Lieferanten an, leere Felder werden weggelassen.
+func ShowSupplierDetails(supplier models.Supplier) {
+	ShowMessage(fmt.Sprintf("* Supplier: %s *", supplier.SupplierName))
+	for _, field := range []struct{ label, value string }{
+		{"Contact person", supplier.ContactPerson},
+		{"Email", supplier.Email},
+		{"Phone", supplier.Phone},
+		{"Address", supplier.Address},
+		{"Website", supplier.Website},
+		{"Customer number", supplier.CustomerNumber},
+		{"Payment terms", supplier.PaymentTerms},
+	} {
+		if field.value != "" {
+			fmt.Printf("  %s: %s\n", field.label, field.value)
+		}
+	}
+}
+
+// *AskForSupplierDetail: Prompts for one field of a supplier, "Enter" keeps the current value and "Space" clears it.
+// *AskForSupplierDetail: Fragt nach einem Feld eines Lieferanten, "Enter" behält den aktuellen Wert und "Space" löscht ihn.
+func AskForSupplierDetail(fieldName, currentValue string, validate func(string) bool) string {
+	return askForOptionalValue(fieldName, currentValue, validate)
+}
+
+// *AskForNumberPattern: Prompts for the prefix, the width and the check digit of the article numbers of a category.
+// *AskForNumberPattern: Fragt nach dem Präfix, der Breite und der Prüfziffer der Artikelnummern einer Kategorie.
+func AskForNumberPattern(current models.NumberPattern) models.NumberPattern {
+	pattern := current
+	pattern.Prefix = askForOptionalValue("Prefix, e.g. M", current.Prefix, func(string) bool { return true })
+
+	width := ""
+	if current.Width > 0 {
+		width = strconv.Itoa(current.Width)
+	}
+	width = askForOptionalValue("Number of digits", width, func(input string) bool {
+		number, err := strconv.Atoi(input)
+		return err == nil && number >= 0
+	})
+	pattern.Width, _ = strconv.Atoi(width)
+
+	checkDigit := "n"
+	if current.CheckDigit {
+		checkDigit = "y"
+	}
+	ShowMessage(fmt.Sprintf("* Append a check digit? (y/n) [Entered: %s]:", checkDigit))
+	if input := strings.ToLower(AskForInput()); input != "" {
+		checkDigit = input
+	}
+	pattern.CheckDigit = checkDigit == "y"
+	return pattern
+}
+
+// *AskForStockLevel: Prompts for the minimum and reorder quantity, inherited is the level that applies without a
+// level of its own. "Enter" keeps the current value, "Space" clears it and 0 turns the inherited minimum off.
+// *AskForStockLevel: Fragt nach dem Mindestbestand und der Bestellmenge, inherited ist der Bestand, der ohne eigenen
+// Bestand gilt. "Enter" behält den aktuellen Wert, "Space" löscht ihn und 0 schaltet den geerbten Mindestbestand ab.
+func AskForStockLevel(current, inherited models.StockLevel) models.StockLevel {
+	if inherited.IsSet() {
+		ShowMessage(fmt.Sprintf("Without a level of its own, the %s of the category apply, a minimum of 0 turns them off.", inherited))
+	}
+	isQuantity := func(input string) bool {
+		number, err := strconv.Atoi(input)
+		return err == nil && number >= 0
+	}
+	asText := func(value int, defined bool) string {
+		if !defined {
+			return ""
+		}
+		return strconv.Itoa(value)
+	}
+
+	minimum := askForOptionalValue("Minimum quantity", asText(current.Minimum, current.IsDefined()), isQuantity)
+	if minimum == "" {
+		return models.StockLevel{}
+	}
+	level := models.StockLevel{Defined: true}
+	level.Minimum, _ = strconv.Atoi(minimum)
+	if level.Minimum == 0 {
+		return level
+	}
+	level.Reorder, _ = strconv.Atoi(askForOptionalValue("Reorder quantity", asText(current.Reorder, current.Reorder > 0), isQuantity))
+	return level
+}
+
+// *StockLevelColumns: Returns the columns with the minimum and the pieces to order of the items.
+// *StockLevelColumns: Gibt die Spalten mit dem Mindestbestand und den zu bestellenden Stücken der Artikel zurück.
+func StockLevelColumns(levelOf func(item models.Item) models.StockLevel) []Column {
+	return []Column{
+		{Header: "Minimum", Value: func(item models.Item) string { return strconv.Itoa(levelOf(item).Minimum) }},
+		{Header: "To order", Value: func(item models.Item) string { return strconv.Itoa(levelOf(item).OrderQuantity(item)) }},
+	}
+}
+
+// *askForOptionalValue: Prompts for an optional value, "Enter" keeps the current value and "Space" clears it.
+// *askForOptionalValue: Fragt nach einem optionalen Wert, "Enter" behält den aktuellen Wert und "Space" löscht ihn.
+func askForOptionalValue(fieldName, currentValue string, validate func(string) bool) string {
+	for {
+		if currentValue != "" {
+			ShowMessage(fmt.Sprintf("* %s [Entered: %s] (\"Enter\" to keep, \"Space\" to clear):", fieldName, currentValue))
+		} else {
+			ShowMessage(fmt.Sprintf("* %s (optional):", fieldName))
+		}
+
+		input := askForRawInput()
+		if input == "" {
+			return currentValue
+		}
+		input = strings.TrimSpace(input)
+		if input == "" || validate(input) {
+			return input
+		}
+		ShowMessage(fmt.Sprintf("⚠️ Invalid %s. Please try again.", strings.ToLower(fieldName)))
+	}
+}
+
+// askForRawInput reads a line without trimming the spaces, so a single space can be told apart from an empty input
+func askForRawInput() string {
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	CheckAndHandleError(err)
+	return strings.TrimRight(input, "\r\n")
+}
+
+// *AskForAttribute: Prompts for the value of an attribute until it fits its type. "Enter" keeps the current value,
+// "Space" clears it.
+// *AskForAttribute: Fragt nach dem Wert eines Attributs, bis er zu seinem Typ passt. "Enter" behält den aktuellen Wert,
+// "Space" löscht ihn.
+func AskForAttribute(definition Attribute.Definition, currentValue string) string {
+	for {
+		if currentValue != "" {
+			ShowMessage(fmt.Sprintf("* %s [Entered: %s] (\"Enter\" to keep, \"Space\" to clear):", definition.Describe(), currentValue))
+		} else {
+			ShowMessage(fmt.Sprintf("* %s:", definition.Describe()))
+		}
+
+		input := askForRawInput()
+		if input == "" && currentValue != "" {
+			return currentValue
+		}
+		value, err := definition.Normalize(input)
+		if err == nil {
+			return value
+		}
+		ShowMessage(fmt.Sprintf("⚠️ %v. Please try again.", err))
+	}
+}
+
+// ShowAttributeDefinitions shows the attributes of a category, the inherited ones are listed first without a number
+func ShowAttributeDefinitions(category string, inherited, own []Attribute.Definition) {
+	ShowMessage(fmt.Sprintf("* Attributes of '%s' *", category))
+	if len(inherited)+len(own) == 0 {
+		ShowMessage("  No attributes defined.")
+	}
+	for _, definition := range inherited {
+		fmt.Printf("   - %s, from %s\n", definition.Describe(), definition.Category)
+	}
+	for i, definition := range own {
+		fmt.Printf("%d. %s\n", i+1, definition.Describe())
+	}
+}
+
+// AskForAttributeAction asks what to do with the attributes of a category
+func AskForAttributeAction() string {
+	ShowMessage("[a] Add an attribute  [d] Delete an attribute  [c] Back")
+	return AskForInput()
+}
+
+// ShowAttributeFilterOptions shows the number of articles found so far and the attributes they can be filtered by
+func ShowAttributeFilterOptions(count int, definitions []Attribute.Definition) {
+	ShowMessage(fmt.Sprintf("%d article(s). Filter by attribute? Enter its number or press [Enter] to show them:", count))
+	for i, definition := range definitions {
+		fmt.Printf("%d. %s\n", i+1, definition.Describe())
+	}
+}
+
+// ShowAttributeTypes shows the attribute types with their index
+func ShowAttributeTypes() {
+	ShowMessage("* Attribute types *")
+	for i, attributeType := range Attribute.Types {
+		fmt.Printf("%d. %s\n", i+1, attributeType)
+	}
+}
+
+// ShowQuantityOperations shows how the stock of an item can be changed
+func ShowQuantityOperations() {
+	ShowMessage("[1] Add\n[2] Subtract\n[3] Serial numbers\n[4] Transfer between locations\n[5] Movement history")
+}
+
+// ShowAssets shows the pieces of an item with their serial number, purchase date, status and notes
+func ShowAssets(assets []models.Asset) {
+	serialLen, statusLen := len("Serial number"), len("Status")
+	for _, asset := range assets {
+		serialLen = max(serialLen, len(asset.SerialNumber))
+		statusLen = max(statusLen, len(assetStatus(asset)))
+	}
+	fmt.Printf("%4s | %-*s | %-10s | %-*s | %s\n", "No.", serialLen, "Serial number", "Purchased", statusLen, "Status", "Notes")
+	ShowMessage(strings.Repeat("-", serialLen+statusLen+35))
+	for i, asset := range assets {
+		fmt.Printf("%4d | %-*s | %-10s | %-*s | %s\n", i+1, serialLen, asset.SerialNumber, asset.PurchaseDate, statusLen, assetStatus(asset), asset.Note)
+	}
+}
+
+// assetStatus returns the status of a piece, checked out pieces with the employee who has them
+func assetStatus(asset models.Asset) string {
+	if asset.Employee != "" {
+		return fmt.Sprintf("%s (%s)", asset.Status, asset.Employee)
+	}
+	return asset.Status
+}
+
+// AskForNewAsset asks for the purchase date and the notes of a new piece, it is booked in as in stock
+func AskForNewAsset(serialNumber string) models.Asset {
+	asset := models.Asset{SerialNumber: serialNumber, Status: models.AssetInStock}
+	today := time.Now().Format(models.AssetDateFormat)
+	for {
+		ShowMessage(fmt.Sprintf("* Purchase date of %s (DD.MM.YYYY) [Enter for today, %s]:", serialNumber, today))
+		asset.PurchaseDate = AskForInput()
+		if asset.PurchaseDate == "" {
+			asset.PurchaseDate = today
+		}
+		err := asset.Validate()
+		if err == nil {
+			break
+		}
+		ShowMessage(fmt.Sprintf("⚠️ %v.", err))
+	}
+	ShowMessage(fmt.Sprintf("* Notes of %s (optional):", serialNumber))
+	asset.Note = AskForInput()
+	return asset
+}
+
+// AskForAssetDetails asks for the new status, purchase date and notes of a piece, "Enter" keeps a value
+func AskForAssetDetails(asset models.Asset) models.Asset {
+	for i, status := range models.AssetStatuses {
+		fmt.Printf("%d. %s\n", i+1, status)
+	}
+	for {
+		ShowMessage(fmt.Sprintf("* Status [Entered: %s], enter its number:", asset.Status))
+		input := AskForInput()
+		if input == "" {
+			break
+		}
+		index, err := strconv.Atoi(input)
+		if err == nil && index >= 1 && index <= len(models.AssetStatuses) {
+			asset.Status = models.AssetStatuses[index-1]
+			break
+		}
+		ShowMessage("⚠️ Invalid status. Please try again.")
+	}
+	asset.PurchaseDate = askForOptionalValue("Purchase date (DD.MM.YYYY)", asset.PurchaseDate, func(input string) bool {
+		_, err := time.Parse(models.AssetDateFormat, input)
+		return err == nil
+	})
+	asset.Note = askForOptionalValue("Notes", asset.Note, func(string) bool { return true })
+	return asset
+}
+
+// ShowEmployees shows the employees of the register with their department and email
+func ShowEmployees(employees []models.Employee) {
+	ShowMessage("* Employees *")
+	if len(employees) == 0 {
+		ShowMessage("  No employees registered.")
+	}
+	for i, employee := range employees {
+		details := slices.DeleteFunc([]string{employee.Department, employee.Email}, func(detail string) bool { return detail == "" })
+		if len(details) > 0 {
+			fmt.Printf("%d. %s (%s)\n", i+1, employee.EmployeeName, strings.Join(details, ", "))
+		} else {
+			fmt.Printf("%d. %s\n", i+1, employee.EmployeeName)
+		}
+	}
+}
+
+// AskForEmployeeAction asks what to do with the employee register
+func AskForEmployeeAction() string {
+	ShowMessage("[a] Add an employee  [d] Delete an employee  [c] Back")
+	return AskForInput()
+}
+
+// AskForEmployeeDetail prompts for one field of an employee, "Enter" keeps the current value and "Space" clears it
+func AskForEmployeeDetail(fieldName, currentValue string, validate func(string) bool) string {
+	return askForOptionalValue(fieldName, currentValue, validate)
+}
+
+// ShowEmployeeHoldings shows the articles an employee has, numbered so one can be chosen
+func ShowEmployeeHoldings(employee string, holdings []models.Holding) {
+	ShowMessage(fmt.Sprintf("* Articles of %s *", employee))
+	nameLen := len("Article")
+	for _, holding := range holdings {
+		nameLen = max(nameLen, len(holding.Item.ArticleName))
+	}
+	fmt.Printf("%4s | %4s | %-*s | %-15s | %6s | %s\n", "No.", "ID", nameLen, "Article", "Article number", "Pieces", "Serial numbers")
+	ShowMessage(strings.Repeat("-", nameLen+55))
+	for i, holding := range holdings {
+		fmt.Printf("%4d | %4d | %-*s | %-15s | %6d | %s\n", i+1, holding.Item.ID, nameLen, holding.Item.ArticleName,
+			holding.Item.ArticleNumber, holding.Count, strings.Join(holding.SerialNumbers, ", "))
+	}
+}
+
+// ShowItemHolders shows which employees have pieces of an article
+func ShowItemHolders(item models.Item, holdings []models.Holding) {
+	ShowMessage(fmt.Sprintf("* %s (ID %d), %d pieces in stock *", item.ArticleName, item.ID, item.Quantity))
+	nameLen := len("Employee")
+	for _, holding := range holdings {
+		nameLen = max(nameLen, len(holding.Employee))
+	}
+	fmt.Printf("%-*s | %6s | %s\n", nameLen, "Employee", "Pieces", "Serial numbers")
+	ShowMessage(strings.Repeat("-", nameLen+32))
+	for _, holding := range holdings {
+		fmt.Printf("%-*s | %6d | %s\n", nameLen, holding.Employee, holding.Count, strings.Join(holding.SerialNumbers, ", "))
+	}
+}
+
+// ShowLocations shows the locations with the number of pieces in stock there
+func ShowLocations(locations []string, items []models.Item) {
+	ShowMessage("* Locations *")
+	if len(locations) == 0 {
+		ShowMessage("  No locations defined.")
+	}
+	for i, location := range locations {
+		pieces, articles := 0, 0
+		for _, item := range models.ItemsAtLocation(items, location) {
+			pieces += item.LocationQuantity(location)
+			articles++
+		}
+		fmt.Printf("%d. %s (%d pieces of %d article(s))\n", i+1, location, pieces, articles)
+	}
+}
+
+// AskForLocationAction asks what to do with the locations
+func AskForLocationAction() string {
+	ShowMessage("[a] Add a location  [r] Rename a location  [d] Delete a location  [c] Back")
+	return AskForInput()
+}
+
+// ShowStockLocations shows the passed locations of the item with the pieces in stock there, numbered so one can be chosen
+func ShowStockLocations(item models.Item, locations []string) {
+	ShowMessage(fmt.Sprintf("* Stock of %s by location *", item.ArticleName))
+	for i, location := range locations {
+		fmt.Printf("%d. %s: %d pieces\n", i+1, models.LocationName(location), item.LocationQuantity(location))
+	}
+}
+
+// ShowLocationFilterOptions shows the number of articles and the locations they can be filtered by
+func ShowLocationFilterOptions(count int, locations []string) {
+	ShowMessage(fmt.Sprintf("%d article(s). Only show the articles at a location? Enter its number or press [Enter] to show all:", count))
+	for i, location := range locations {
+		fmt.Printf("%d. %s\n", i+1, location)
+	}
+}
+
+// LocationColumn returns the column with the pieces of the items at the location
+func LocationColumn(location string) Column {
+	return Column{Header: fmt.Sprintf("At %s [pcs]", location), Value: func(item models.Item) string {
+		return strconv.Itoa(item.LocationQuantity(location))
+	}}
+}
+
+// LocationsColumn returns the column that shows how the stock of the items is split across the locations
+func LocationsColumn() Column {
+	return Column{Header: "Locations", Value: func(item models.Item) string {
+		if len(item.Locations) == 0 {
+			return ""
+		}
+		var parts []string
+		for _, location := range slices.Sorted(maps.Keys(item.Locations)) {
+			parts = append(parts, fmt.Sprintf("%s %d", location, item.Locations[location]))
+		}
+		if unassigned := item.Unassigned(); unassigned > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", models.LocationName(models.NoLocation), unassigned))
+		}
+		return strings.Join(parts, ", ")
+	}}
+}
+
+// AskForMovement asks for the reason, the reference number and a comment of a booking, the first reason is the default
+func AskForMovement(reasons []string) Ledger.Movement {
+	movement := Ledger.Movement{Reason: reasons[0]}
+	for i, reason := range reasons {
+		fmt.Printf("%d. %s\n", i+1, reason)
+	}
+	for {
+		ShowMessage(fmt.Sprintf("* Reason [Enter for %s], enter its number:", reasons[0]))
+		input := AskForInput()
+		if input == "" {
+			break
+		}
+		index, err := strconv.Atoi(input)
+		if err == nil && index >= 1 && index <= len(reasons) {
+			movement.Reason = reasons[index-1]
+			break
+		}
+		ShowMessage("⚠️ Invalid reason. Please try again.")
+	}
+	ShowMessage("* Reference number, e.g. of the order or delivery note (optional):")
+	movement.Reference = AskForInput()
+	ShowMessage("* Comment (optional):")
+	movement.Comment = AskForInput()
+	return movement
+}
+
+// ShowMovements shows the movements of an item with the stock after each of them, balance is the stock before the first
+func ShowMovements(movements []Ledger.Movement, balance int) {
+	fmt.Printf("%-21s | %-10s | %6s | %5s | %-10s | %-12s | %-12s | %s\n", "Date", "User", "Amount", "Stock", "Reason", "Location", "Reference", "Comment")
+	ShowMessage(strings.Repeat("-", 105))
+	for _, movement := range movements {
+		balance += movement.Amount
+		fmt.Printf("%-21s | %-10s | %+6d | %5d | %-10s | %-12s | %-12s | %s\n", movement.Time.Local().Format("02.01.2006 / 15:04:05"),
+			movement.User, movement.Amount, balance, movement.Reason, movement.Location, movement.Reference, movement.Comment)
+	}
+}